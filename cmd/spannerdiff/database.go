@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// readDatabaseDDL reads the current schema of the database (projects/P/instances/I/databases/D) via gcloud,
+// so that the Spanner client is not a dependency of spannerdiff.
+func readDatabaseDDL(ctx context.Context, name string) (io.Reader, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "gcloud", "spanner", "databases", "ddl", "describe", name, "--format=json")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("gcloud spanner databases ddl describe %s: %s", name, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("failed to run gcloud: %w", err)
+	}
+
+	var stmts []string
+	if err := json.Unmarshal(stdout.Bytes(), &stmts); err != nil {
+		return nil, fmt.Errorf("failed to decode database DDL: %w", err)
+	}
+	var b strings.Builder
+	for _, stmt := range stmts {
+		b.WriteString(stmt)
+		b.WriteString(";\n")
+	}
+	return strings.NewReader(b.String()), nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// readGitFile reads the file at the revision specified as <ref>:<path> via git show.
+func readGitFile(ctx context.Context, revPath string) (io.Reader, error) {
+	if ref, path, ok := strings.Cut(revPath, ":"); !ok || ref == "" || path == "" {
+		return nil, fmt.Errorf("invalid git revision and path, want <ref>:<path>: %s", revPath)
+	}
+	if strings.HasPrefix(revPath, "-") {
+		// Otherwise git would take it as an option.
+		return nil, fmt.Errorf("invalid git revision, must not start with '-': %s", revPath)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "show", revPath)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git show %s: %s", revPath, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("failed to run git: %w", err)
+	}
+	return &stdout, nil
+}
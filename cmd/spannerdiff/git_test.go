@@ -0,0 +1,14 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadGitFile_Option(t *testing.T) {
+	_, err := readGitFile(context.Background(), "--output=/tmp/x:schema.sql")
+	if err == nil || !strings.Contains(err.Error(), "must not start with '-'") {
+		t.Errorf("want error for a revision starting with '-', got %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/morikuni/aec"
 	"github.com/spf13/pflag"
@@ -25,19 +30,52 @@ func realMain(args []string, stdin io.Reader, stdout *os.File, stderr io.Writer)
 	globalFlags := pflag.NewFlagSet("", pflag.ContinueOnError)
 	globalFlags.SortFlags = false
 	color := globalFlags.StringP("color", "", "auto", "color mode [auto, always, never]")
+	colorTheme := globalFlags.StringP("color-theme", "", "default", "color theme ["+strings.Join(spannerdiff.ColorThemeNames(), ", ")+"]")
+	orderBy := globalFlags.StringP("order-by", "", "id", "statement order [id, type]")
+	groupByObject := globalFlags.BoolP("group-by-object", "", false, "place statements on the same object next to each other where the dependency order allows")
+	ignore := globalFlags.StringArrayP("ignore", "", nil, "ignore definitions whose identifier matches the glob pattern, e.g. 'Table(Audit*)' (can be repeated)")
+	annotateDrops := globalFlags.BoolP("annotate-drops", "", false, "add comments listing dependents dropped together")
+	hints := globalFlags.BoolP("hints", "", false, "add comments explaining data lost by each statement")
+	warnDestructive := globalFlags.BoolP("warn-destructive", "", false, "warn about changes losing data")
+	warnUndefined := globalFlags.BoolP("warn-undefined-references", "", false, "warn about indexes, views and grants referencing undefined objects")
+	maxDrops := globalFlags.IntP("max-drops", "", -1, "fail with exit code 3 if there are more destructive changes than N (-1 means unlimited)")
+	safeOnly := globalFlags.BoolP("safe-only", "", false, "skip destructive changes (drop and recreate)")
+	splitBatches := globalFlags.BoolP("split-batches", "", false, "start a new batch where a dropped definition is added again, marked by a comment")
+	additiveOnly := globalFlags.BoolP("additive-only", "", false, "skip all changes removing something from the schema (the result may not match the target)")
+	safeTypeChange := globalFlags.BoolP("safe-type-change", "", false, "change column types via a temporary column <column>_new instead of dropping the column (the schema does not converge to the target)")
+	enumTypes := globalFlags.StringArrayP("enum-type", "", nil, "full name of a named type which is an ENUM, e.g. 'examples.music.Genre' (can be repeated, other named types are PROTOs)")
+	idempotentGrants := globalFlags.BoolP("idempotent-grants", "", false, "emit a REVOKE before each GRANT to make grants re-runnable")
+	deduplicate := globalFlags.BoolP("deduplicate", "", false, "remove statements identical to the immediately preceding statement (non-adjacent duplicates are kept)")
+	profile := globalFlags.BoolP("profile", "", false, "print the elapsed time of each phase to stderr")
+	stmtRange := globalFlags.StringP("range", "", "", "print only statements N through M (1-based), e.g. 1:10")
+	maxWidth := globalFlags.IntP("max-width", "", 0, "wrap lines longer than the width at commas (0 means no wrapping)")
+	finalNewline := globalFlags.StringP("final-newline", "", "one", "how the output ends [one, none]")
+	typeCase := globalFlags.StringP("type-case", "", "", "case of type names [upper, lower] (default as rendered)")
+	qualifySchema := globalFlags.StringP("qualify-schema", "", "", "prefix unqualified names of tables, indexes and views in the output with the schema")
+	createAll := globalFlags.BoolP("create-all", "", false, "print statements creating the whole target schema, same as an empty base")
+	withDown := globalFlags.StringP("with-down", "", "", "also write the reverse migration from target to base to the file")
+	join := globalFlags.BoolP("join", "", false, "print statements as a JSON array of strings for UpdateDatabaseDdl")
+	format := globalFlags.StringP("format", "", "sql", "output format [sql, json-detailed]")
+	dumpBase := globalFlags.BoolP("dump-base", "", false, "print the normalized base schema instead of the diff")
+	dumpTarget := globalFlags.BoolP("dump-target", "", false, "print the normalized target schema instead of the diff")
+	check := globalFlags.BoolP("check", "", false, "exit with 1 if there are differences, 2 if a schema has unsupported DDL or can't be parsed, or 4 on other errors")
+	header := globalFlags.BoolP("header", "", false, "print a header comment with version and time (SQL output only)")
+	quiet := globalFlags.BoolP("quiet", "q", false, "suppress informational messages")
 	versionFlag := globalFlags.BoolP("version", "", false, "print version")
 
 	baseFlags := pflag.NewFlagSet("", pflag.ContinueOnError)
 	baseFlags.SortFlags = false
 	baseDDL := baseFlags.StringP("base", "", "", "base schema")
-	baseFile := baseFlags.StringP("base-file", "", "", "read base schema from file")
+	baseFile := baseFlags.StringP("base-file", "", "", "read base schema from file (decompressed if it ends with .gz)")
 	baseStdin := baseFlags.BoolP("base-stdin", "", false, "read base schema from stdin")
+	baseDatabase := baseFlags.StringP("base-database", "", "", "read base schema from database (projects/P/instances/I/databases/D) via gcloud")
 
 	targetFlags := pflag.NewFlagSet("", pflag.ContinueOnError)
 	targetFlags.SortFlags = false
 	targetDDL := targetFlags.StringP("target", "", "", "target schema")
-	targetFile := targetFlags.StringP("target-file", "", "", "read target schema from file")
+	targetFile := targetFlags.StringP("target-file", "", "", "read target schema from file (decompressed if it ends with .gz)")
 	targetStdin := targetFlags.BoolP("target-stdin", "", false, "read target schema from stdin")
+	targetGit := targetFlags.StringP("target-git", "", "", "read target schema from git revision (<ref>:<path>, e.g. HEAD:schema.sql)")
 
 	rootFlags := pflag.NewFlagSet(args[0], pflag.ContinueOnError)
 	rootFlags.SortFlags = false
@@ -70,12 +108,29 @@ func realMain(args []string, stdin io.Reader, stdout *os.File, stderr io.Writer)
 		)
 	}
 
+	// With --check, 1 and 2 are reserved for differences and for unsupported or unparsable schemas,
+	// so the other failures exit with 4.
+	failed := func(code int) int {
+		if *check {
+			return 4
+		}
+		return code
+	}
+	errorCode := func(err error) int {
+		var pe *spannerdiff.ParseError
+		var ue *spannerdiff.UnsupportedDDLError
+		if *check && (errors.As(err, &pe) || errors.As(err, &ue)) {
+			return 2
+		}
+		return failed(1)
+	}
+
 	if err := rootFlags.Parse(args[1:]); err != nil {
 		if errors.Is(err, pflag.ErrHelp) {
 			return 0
 		}
 		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(err.Error()))
-		return 2
+		return failed(2)
 	}
 
 	if *versionFlag {
@@ -83,9 +138,24 @@ func realMain(args []string, stdin io.Reader, stdout *os.File, stderr io.Writer)
 		return 0
 	}
 
+	if *dumpBase && *dumpTarget {
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply("cannot specify both --dump-base and --dump-target"))
+		return failed(1)
+	}
+
+	if *createAll && (*baseDDL != "" || *baseFile != "" || *baseStdin || *baseDatabase != "") {
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply("cannot specify base schema with --create-all"))
+		return failed(1)
+	}
+
+	if *header && *join {
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply("cannot specify both --header and --join"))
+		return failed(1)
+	}
+
 	if *baseStdin && *targetStdin {
 		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply("cannot specify both --base-stdin and --target-stdin"))
-		return 1
+		return failed(1)
 	}
 
 	var base, target io.Reader
@@ -96,29 +166,49 @@ func realMain(args []string, stdin io.Reader, stdout *os.File, stderr io.Writer)
 		target = stdin
 	}
 	if *baseFile != "" {
-		f, err := os.Open(*baseFile)
+		f, err := openFile(*baseFile)
 		if err != nil {
 			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("failed to open base DDL file: %v", err)))
-			return 2
+			return failed(2)
 		}
 		defer func() {
 			_ = f.Close()
 		}()
 		base = f
 	}
+	if *baseDatabase != "" {
+		r, err := readDatabaseDDL(context.Background(), *baseDatabase)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("failed to read base DDL from database: %v", err)))
+			return failed(2)
+		}
+		base = r
+	}
 	if *targetFile != "" {
-		f, err := os.Open(*targetFile)
+		f, err := openFile(*targetFile)
 		if err != nil {
 			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("failed to open target DDL file: %v", err)))
-			return 2
+			return failed(2)
 		}
 		defer func() {
 			_ = f.Close()
 		}()
 		target = f
 	}
+	if *targetGit != "" {
+		r, err := readGitFile(context.Background(), *targetGit)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("failed to read target DDL from git: %v", err)))
+			return failed(2)
+		}
+		target = r
+	}
+	var notSpecified bool
 	if base == nil && *baseDDL == "" && target == nil && *targetDDL == "" {
-		_, _ = fmt.Fprintln(stderr, aec.YellowF.Apply("both base and target schema are not specified"))
+		notSpecified = true
+		if !*quiet {
+			_, _ = fmt.Fprintln(stderr, aec.YellowF.Apply("both base and target schema are not specified"))
+		}
 	}
 	if base == nil {
 		base = strings.NewReader(*baseDDL)
@@ -132,13 +222,269 @@ func realMain(args []string, stdin io.Reader, stdout *os.File, stderr io.Writer)
 		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("invalid color mode: %s", *color)))
 	}
 
-	err := spannerdiff.Diff(base, target, stdout, spannerdiff.DiffOption{
-		Printer: spannerdiff.DetectTerminalPrinter(cm, stdout),
-	})
+	ob, ok := spannerdiff.NewOrderBy(*orderBy)
+	if !ok {
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("invalid order: %s", *orderBy)))
+		return failed(2)
+	}
+
+	var sr *spannerdiff.StatementRange
+	if *stmtRange != "" {
+		r, err := spannerdiff.ParseStatementRange(*stmtRange)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(err.Error()))
+			return failed(2)
+		}
+		sr = &r
+	}
+
+	var tc spannerdiff.TypeCase
+	if *typeCase != "" {
+		tc, ok = spannerdiff.NewTypeCase(*typeCase)
+		if !ok {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("invalid type case: %s", *typeCase)))
+			return failed(2)
+		}
+	}
+
+	fn, ok := spannerdiff.NewFinalNewline(*finalNewline)
+	if !ok {
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("invalid final newline: %s", *finalNewline)))
+		return failed(2)
+	}
+
+	switch *format {
+	case "sql", "json-detailed":
+	default:
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("invalid format: %s", *format)))
+		return failed(2)
+	}
+	if *header && *format != "sql" {
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("cannot specify --header with --format=%s", *format)))
+		return failed(1)
+	}
+	if *join && *format != "sql" {
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("cannot specify --join with --format=%s", *format)))
+		return failed(1)
+	}
+
+	style, ok := spannerdiff.ColorThemeStyle(*colorTheme)
+	if !ok {
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("invalid color theme: %s", *colorTheme)))
+		return failed(2)
+	}
+	printerOpts := spannerdiff.DefaultPrinterOptions()
+	printerOpts.Style = style
+	printer, err := spannerdiff.NewPrinter(cm, stdout, printerOpts)
 	if err != nil {
 		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(err.Error()))
-		return 1
+		return failed(2)
+	}
+	if *maxWidth > 0 {
+		printer = spannerdiff.WithMaxWidth(*maxWidth, printer)
 	}
 
+	option := spannerdiff.DiffOption{
+		Printer:                 printer,
+		OrderBy:                 ob,
+		Ignore:                  *ignore,
+		EnumTypes:               *enumTypes,
+		AnnotateDrops:           *annotateDrops,
+		Hints:                   *hints,
+		WarnDestructive:         *warnDestructive,
+		WarnUndefinedReferences: *warnUndefined,
+		SafeOnly:                *safeOnly,
+		AdditiveOnly:            *additiveOnly,
+		TypeCase:                tc,
+		SplitBatches:            *splitBatches,
+		SafeTypeChange:          *safeTypeChange,
+		IdempotentGrants:        *idempotentGrants,
+		GroupByObject:           *groupByObject,
+		QualifySchema:           *qualifySchema,
+		Deduplicate:             *deduplicate,
+		Range:                   sr,
+		FinalNewline:            fn,
+	}
+	if *profile {
+		option.Profile = stderr
+	}
+	if *check {
+		option.ErrorOnUnsupportedDDL = true
+	}
+	if *maxDrops >= 0 {
+		option.MaxDrops = maxDrops
+	}
+
+	// The SQL output is buffered to print the header only if the statements are generated.
+	var out io.Writer = stdout
+	var sqlBuf bytes.Buffer
+	if *header {
+		out = &sqlBuf
+	}
+	writeHeader := func() error {
+		if !*header {
+			return nil
+		}
+		if _, err := fmt.Fprintf(stdout, "-- Generated by spannerdiff %s at %s\n", version, time.Now().Format(time.RFC3339)); err != nil {
+			return err
+		}
+		_, err := sqlBuf.WriteTo(stdout)
+		return err
+	}
+
+	if *dumpBase || *dumpTarget {
+		schema := target
+		if *dumpBase {
+			schema = base
+		}
+		if err := spannerdiff.Dump(schema, out, option); err != nil {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(err.Error()))
+			return errorCode(err)
+		}
+		if err := writeHeader(); err != nil {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(err.Error()))
+			return failed(1)
+		}
+		return 0
+	}
+
+	// The schemas are read twice to generate the down migration.
+	var down []byte
+	if *withDown != "" {
+		baseSQL, err := io.ReadAll(base)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("failed to read base DDL: %v", err)))
+			return failed(2)
+		}
+		targetSQL, err := io.ReadAll(target)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("failed to read target DDL: %v", err)))
+			return failed(2)
+		}
+		base, target = bytes.NewReader(baseSQL), bytes.NewReader(targetSQL)
+		// The down migration is generated first, so that the forward migration is not printed if it fails.
+		down, err = diffDown(baseSQL, targetSQL, option, *maxWidth)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(err.Error()))
+			return errorCode(err)
+		}
+	}
+
+	var result spannerdiff.DiffResult
+	if *format == "json-detailed" {
+		var ops []spannerdiff.PlannedOperation
+		ops, result, err = spannerdiff.PlanOperations(base, target, option)
+		if err == nil {
+			enc := json.NewEncoder(stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(ops)
+		}
+	} else if *join {
+		var ddls []string
+		ddls, result, err = spannerdiff.PlanDDLs(base, target, option)
+		if err == nil {
+			enc := json.NewEncoder(stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(ddls)
+		}
+	} else {
+		result, err = spannerdiff.DiffWithResult(base, target, out, option)
+		if err == nil {
+			err = writeHeader()
+		}
+	}
+	if err == nil && *withDown != "" {
+		if err = os.WriteFile(*withDown, down, 0o644); err != nil {
+			err = fmt.Errorf("failed to write down migration file: %w", err)
+		}
+	}
+	if err != nil {
+		var tde *spannerdiff.TooManyDropsError
+		if errors.As(err, &tde) {
+			_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(fmt.Sprintf("%d destructive changes exceed --max-drops=%d:", len(tde.Drops), tde.Max)))
+			for _, d := range tde.Drops {
+				_, _ = fmt.Fprintln(stderr, aec.RedF.Apply("  "+d))
+			}
+			return 3
+		}
+		_, _ = fmt.Fprintln(stderr, aec.RedF.Apply(err.Error()))
+		return errorCode(err)
+	}
+
+	for _, w := range result.Warnings {
+		_, _ = fmt.Fprintln(stderr, aec.YellowF.Apply("warning: "+w))
+	}
+
+	if len(result.Skipped) > 0 {
+		_, _ = fmt.Fprintln(stderr, aec.YellowF.Apply(fmt.Sprintf("skipped %d changes:", len(result.Skipped))))
+		for _, s := range result.Skipped {
+			_, _ = fmt.Fprintln(stderr, aec.YellowF.Apply("  "+s))
+		}
+	}
+
+	if !*quiet {
+		switch {
+		case result.EmptySchemas:
+			if !notSpecified {
+				_, _ = fmt.Fprintln(stderr, "both base and target schema are empty")
+			}
+		case result.Statements == 0:
+			_, _ = fmt.Fprintln(stderr, "no differences")
+		}
+	}
+
+	if *check && (result.Statements > 0 || len(result.Skipped) > 0) {
+		// Skipped changes are differences not printed by --safe-only or --additive-only.
+		return 1
+	}
 	return 0
 }
+
+// diffDown returns the migration from target back to base.
+// It can't restore data lost by the forward migration, e.g. a dropped table is created again empty.
+// The options selecting or limiting the forward migration, such as --range and --max-drops, are not applied.
+func diffDown(base, target []byte, option spannerdiff.DiffOption, maxWidth int) ([]byte, error) {
+	printer := spannerdiff.WithSpacer(spannerdiff.DefaultPrinterOptions().Spacer, spannerdiff.NoStylePrinter{})
+	if maxWidth > 0 {
+		printer = spannerdiff.WithMaxWidth(maxWidth, printer)
+	}
+	option.Printer = printer
+	option.Range = nil
+	option.MaxDrops = nil
+	// The warnings are the same as the forward migration's.
+	option.WarnWriter = nil
+	option.Profile = nil
+	var buf bytes.Buffer
+	if err := spannerdiff.Diff(bytes.NewReader(target), bytes.NewReader(base), &buf, option); err != nil {
+		return nil, fmt.Errorf("failed to generate down migration: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// openFile opens the file, and decompresses it if the name ends with .gz.
+func openFile(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return f, nil
+	}
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return gzipFile{gr, f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (f gzipFile) Close() error {
+	return errors.Join(f.Reader.Close(), f.file.Close())
+}
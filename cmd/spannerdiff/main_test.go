@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// run runs the command with args and returns the exit code and the output to stdout.
+func run(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	stdout, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("failed to create stdout: %v", err)
+	}
+	defer func() {
+		_ = stdout.Close()
+	}()
+	var stderr bytes.Buffer
+	code := realMain(append([]string{"spannerdiff"}, args...), strings.NewReader(""), stdout, &stderr)
+	out, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatalf("failed to read stdout: %v", err)
+	}
+	return code, string(out)
+}
+
+func TestRealMain_ExitCode(t *testing.T) {
+	const table = `CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);`
+	const index = `CREATE INDEX IDX1 ON T1(T1_I1);`
+	for name, tt := range map[string]struct {
+		args []string
+		want int
+	}{
+		"no differences": {
+			[]string{"--check", "--base", table, "--target", table},
+			0,
+		},
+		"differences": {
+			[]string{"--check", "--base", "", "--target", table},
+			1,
+		},
+		"differences without check": {
+			[]string{"--base", "", "--target", table},
+			0,
+		},
+		"skipped by safe-only": {
+			[]string{"--check", "--safe-only", "--base", table, "--target", ""},
+			1,
+		},
+		"skipped by additive-only": {
+			[]string{"--check", "--additive-only", "--base", table, "--target", ""},
+			1,
+		},
+		"parse error": {
+			[]string{"--check", "--base", "", "--target", "CREATE TABLE"},
+			2,
+		},
+		"parse error without check": {
+			[]string{"--base", "", "--target", "CREATE TABLE"},
+			1,
+		},
+		"unsupported DDL": {
+			[]string{"--check", "--base", "", "--target", "ALTER INDEX IDX1 ADD STORED COLUMN T1_I1;"},
+			2,
+		},
+		"too many drops": {
+			[]string{"--check", "--max-drops", "0", "--base", table, "--target", ""},
+			3,
+		},
+		"reference to dropped definition": {
+			[]string{"--check", "--base", table + index, "--target", index},
+			4,
+		},
+		"file not found": {
+			[]string{"--check", "--base-file", filepath.Join(t.TempDir(), "none.sql"), "--target", table},
+			4,
+		},
+		"invalid flags": {
+			[]string{"--check", "--dump-base", "--dump-target"},
+			4,
+		},
+		"invalid flags without check": {
+			[]string{"--dump-base", "--dump-target"},
+			1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got, _ := run(t, tt.args...); got != tt.want {
+				t.Errorf("want exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRealMain_JSONDetailed(t *testing.T) {
+	code, out := run(t, "--format", "json-detailed", "--base", "", "--target", `CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);`)
+	if code != 0 {
+		t.Fatalf("want exit code 0, got %d", code)
+	}
+	var ops []map[string]any
+	if err := json.Unmarshal([]byte(out), &ops); err != nil {
+		t.Fatalf("want JSON array of operations, got %q: %v", out, err)
+	}
+	want := []map[string]any{{
+		"kind":      "add",
+		"id":        "Table(T1)",
+		"ddl":       "CREATE TABLE T1 (\n  T1_I1 INT64 NOT NULL\n) PRIMARY KEY (T1_I1)",
+		"dependsOn": []any{},
+	}}
+	if diff := cmp.Diff(want, ops); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+
+	if code, _ := run(t, "--format", "json-detailed", "--join", "--base", "", "--target", ""); code != 1 {
+		t.Errorf("want exit code 1 for --join with --format=json-detailed, got %d", code)
+	}
+}
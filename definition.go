@@ -1,8 +1,10 @@
 package spannerdiff
 
 import (
-	"errors"
+	"cmp"
 	"fmt"
+	"maps"
+	"path"
 	"slices"
 	"strings"
 
@@ -34,6 +36,8 @@ var _ = []definition{
 	&role{},
 	&grant{},
 	&database{},
+	&localityGroup{},
+	&customDefinition{},
 }
 
 type merger interface {
@@ -42,6 +46,7 @@ type merger interface {
 
 var _ = []merger{
 	&grant{},
+	&database{},
 }
 
 type definitions struct {
@@ -70,65 +75,249 @@ func newDefinitions(ddls []ast.DDL, errorOnUnsupported bool) (*definitions, erro
 	}
 
 	for _, ddl := range ddls {
-		switch ddl := ddl.(type) {
-		case *ast.CreateSchema:
-			add(newSchema(ddl))
-		case *ast.CreateTable:
-			table := newTable(ddl)
-			add(table)
-			for _, col := range table.columns() {
-				add(newColumn(table, col))
-			}
-		case *ast.CreateIndex:
-			add(newIndex(ddl))
-		case *ast.CreateSearchIndex:
-			add(newSearchIndex(ddl))
-		case *ast.CreatePropertyGraph:
-			add(newPropertyGraph(ddl))
-		case *ast.CreateView:
-			add(newView(ddl))
-		case *ast.CreateChangeStream:
-			add(newChangeStream(ddl))
-		case *ast.CreateSequence:
-			add(newSequence(ddl))
-		case *ast.CreateVectorIndex:
-			add(newVectorIndex(ddl))
-		case *ast.CreateModel:
-			add(newModel(ddl))
-		case *ast.CreateProtoBundle:
-			add(newProtoBundle(ddl))
-		case *ast.CreateRole:
-			add(newRole(ddl))
-		case *ast.Grant:
-			for _, g := range newGrant(ddl) {
-				add(g)
-			}
-		case *ast.AlterDatabase:
-			add(newDatabase(ddl))
-		default:
-			if errorOnUnsupported {
-				return nil, fmt.Errorf("unsupported DDL: %s", ddl.SQL())
-			}
+		defs, ok := definitionsOf(ddl)
+		if !ok && errorOnUnsupported {
+			return nil, &UnsupportedDDLError{DDL: ddl.SQL()}
+		}
+		for _, def := range defs {
+			add(def)
+		}
+	}
+
+	for _, def := range d.all {
+		if c, ok := def.(*customDefinition); ok {
+			c.resolveDependencies(d)
 		}
 	}
 
 	if duplicated != nil {
-		var b strings.Builder
-		b.WriteString("duplicated definition found: ")
-		var count int
+		ids := make([]string, 0, len(duplicated))
 		for id := range duplicated {
-			if count > 0 {
-				b.WriteString(", ")
-			}
-			b.WriteString(id.String())
-			count++
+			ids = append(ids, id.String())
 		}
-		return nil, errors.New(b.String())
+		slices.Sort(ids)
+		return nil, &DuplicateDefinitionError{ids}
 	}
 
 	return d, nil
 }
 
+// definitionsOf returns the definitions created by the DDL.
+// It returns false if the DDL is not supported.
+func definitionsOf(ddl ast.DDL) ([]definition, bool) {
+	switch ddl := ddl.(type) {
+	case *ast.CreateSchema:
+		return []definition{newSchema(ddl)}, true
+	case *ast.CreateTable:
+		table := newTable(ddl)
+		defs := []definition{table}
+		for _, col := range table.columns() {
+			defs = append(defs, newColumn(table, col))
+		}
+		return defs, true
+	case *ast.CreateIndex:
+		return []definition{newIndex(ddl)}, true
+	case *ast.CreateSearchIndex:
+		return []definition{newSearchIndex(ddl)}, true
+	case *ast.CreatePropertyGraph:
+		return []definition{newPropertyGraph(ddl)}, true
+	case *ast.CreateView:
+		return []definition{newView(ddl)}, true
+	case *ast.CreateChangeStream:
+		return []definition{newChangeStream(ddl)}, true
+	case *ast.CreateSequence:
+		return []definition{newSequence(ddl)}, true
+	case *ast.CreateVectorIndex:
+		return []definition{newVectorIndex(ddl)}, true
+	case *ast.CreateModel:
+		return []definition{newModel(ddl)}, true
+	case *ast.CreateProtoBundle:
+		return []definition{newProtoBundle(ddl)}, true
+	case *ast.CreateRole:
+		return []definition{newRole(ddl)}, true
+	case *ast.Grant:
+		var defs []definition
+		for _, g := range newGrant(ddl) {
+			defs = append(defs, g)
+		}
+		return defs, true
+	case *ast.AlterDatabase:
+		return []definition{newDatabase(ddl)}, true
+	case *ast.CreateLocalityGroup:
+		return []definition{newLocalityGroup(ddl)}, true
+	default:
+		for _, h := range customHandlers {
+			if !h.match(ddl) {
+				continue
+			}
+			var defs []definition
+			for _, def := range h.construct(ddl) {
+				defs = append(defs, newCustomDefinition(def))
+			}
+			return defs, true
+		}
+		return nil, false
+	}
+}
+
+// Definition is a schema object of a DDL type that spannerdiff doesn't support natively.
+// It is registered by RegisterDefinition.
+type Definition interface {
+	// ID returns the unique name of the definition, e.g. "Placement(P1)".
+	ID() string
+	// Create returns the DDL to create the definition.
+	// Two definitions with the same ID are regarded as equal if their Create DDLs are equal.
+	Create() ast.DDL
+	// Drop returns the DDL to drop the definition, or nil if it can't be dropped.
+	Drop() ast.DDL
+	// Alter returns the DDLs to change the definition to the target.
+	// If it returns nil, the definition is recreated.
+	Alter(target Definition) []ast.DDL
+	// DependsOn returns the IDs of the definitions it depends on, e.g. "Table(T1)".
+	// The definition is recreated when any of them is recreated.
+	DependsOn() []string
+}
+
+type customHandler struct {
+	match     func(ast.DDL) bool
+	construct func(ast.DDL) []Definition
+}
+
+var customHandlers []customHandler
+
+// RegisterDefinition registers a handler for DDLs that spannerdiff doesn't support natively.
+// The DDLs for which match returns true are converted to definitions by construct.
+// Handlers are consulted in the order of registration, only for unsupported DDLs.
+// It is not safe for concurrent use, so it should be called in an init function.
+func RegisterDefinition(match func(ast.DDL) bool, construct func(ast.DDL) []Definition) {
+	customHandlers = append(customHandlers, customHandler{match, construct})
+}
+
+// ignore removes definitions whose identifier matches any of the glob patterns,
+// and also definitions depending on them.
+func (d *definitions) ignore(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid ignore pattern: %s: %w", p, err)
+		}
+	}
+
+	ignored := make(map[identifier]struct{})
+	isIgnored := func(id identifier) bool {
+		if _, ok := ignored[id]; ok {
+			return true
+		}
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, id.String()); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for id, def := range d.all {
+			if isIgnored(id) || slices.ContainsFunc(def.dependsOn(), isIgnored) {
+				ignored[id] = struct{}{}
+				delete(d.all, id)
+				changed = true
+			}
+		}
+	}
+	return nil
+}
+
+// builtinRoles are the roles defined by Spanner, which are not in the schema.
+var builtinRoles = []string{"public", "spanner_info_reader", "spanner_sys_reader"}
+
+// reference is a reference from a definition to another definition.
+type reference struct {
+	from identifier
+	to   identifier
+}
+
+// undefinedReferences returns references of indexes, views and grants to definitions not in d, in sorted order.
+func (d *definitions) undefinedReferences() []reference {
+	var refs []reference
+	exists := func(ids ...identifier) bool {
+		for _, id := range ids {
+			if _, ok := d.all[id]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	check := func(def definition, ids ...identifier) {
+		if !exists(ids...) {
+			refs = append(refs, reference{def.id(), ids[0]})
+		}
+	}
+	for _, def := range d.all {
+		switch def := def.(type) {
+		case *index:
+			if !exists(def.tableID()) {
+				check(def, def.tableID())
+				continue
+			}
+			for _, id := range def.columnIDs() {
+				check(def, id)
+			}
+		case *searchIndex:
+			check(def, def.tableID())
+		case *vectorIndex:
+			check(def, def.tableID())
+		case *view:
+			ctes := make(map[string]bool)
+			ast.Inspect(def.node.Query, func(n ast.Node) bool {
+				if cte, ok := n.(*ast.CTE); ok {
+					ctes[cte.Name.Name] = true
+				}
+				return true
+			})
+			paths, idents := tablesOrViewsInQueryExpr(def.node.Query)
+			for _, ident := range idents {
+				if !ctes[ident.Name] {
+					check(def, newTableIDFromIdent(ident), newViewIDFromIdent(ident))
+				}
+			}
+			for _, path := range paths {
+				if len(path.Idents) == 2 && slices.Contains([]string{"INFORMATION_SCHEMA", "SPANNER_SYS"}, strings.ToUpper(path.Idents[0].Name)) {
+					continue
+				}
+				check(def, newTableIDFromPath(path), newViewIDFromPath(path))
+			}
+		case *grant:
+			roles := slices.Clone(def.node.Roles)
+			switch p := def.node.Privilege.(type) {
+			case *ast.PrivilegeOnTable:
+				for _, name := range p.Names {
+					check(def, newTableIDFromIdent(name))
+				}
+			case *ast.SelectPrivilegeOnView:
+				for _, name := range p.Names {
+					check(def, newViewIDFromIdent(name))
+				}
+			case *ast.SelectPrivilegeOnChangeStream:
+				for _, name := range p.Names {
+					check(def, newChangeStreamID(name))
+				}
+			case *ast.RolePrivilege:
+				roles = append(roles, p.Names...)
+			}
+			for _, r := range roles {
+				if !slices.Contains(builtinRoles, r.Name) {
+					check(def, newRoleID(r))
+				}
+			}
+		}
+	}
+	slices.SortFunc(refs, func(a, b reference) int {
+		return cmp.Or(strings.Compare(a.from.String(), b.from.String()), strings.Compare(a.to.String(), b.to.String()))
+	})
+	return refs
+}
+
 type schema struct {
 	node *ast.CreateSchema
 }
@@ -210,7 +399,7 @@ func (t *table) alter(tgt definition, m *migration) {
 	// - Add or remove a synonym from an existing table.
 	// - Add, replace or remove a row deletion policy from an existing table.
 
-	if !equalNodes(base.node.PrimaryKeys, target.node.PrimaryKeys) {
+	if !equalNodes(base.node.PrimaryKeys, target.node.PrimaryKeys) || !canAlterOptions(base, base.node.Options, target.node.Options) {
 		m.updateStateIfUndefined(newDropAndAddState(base, target))
 		return
 	}
@@ -235,6 +424,10 @@ func (t *table) alter(tgt definition, m *migration) {
 			ddls = append(ddls, &ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.ReplaceRowDeletionPolicy{RowDeletionPolicy: target.node.RowDeletionPolicy.RowDeletionPolicy}})
 		}
 	}
+	if !equalNode(base.node.Options, target.node.Options) {
+		ddls = append(ddls, &ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.AlterTableSetOptions{Options: optionsToSet(base.node.Options, target.node.Options)}})
+	}
+	// Map keys are sorted so that the output is reproducible.
 	if !equalNodes(base.node.Synonyms, target.node.Synonyms) {
 		baseSynonyms := make(map[string]struct{}, len(base.node.Synonyms))
 		for _, syn := range base.node.Synonyms {
@@ -244,48 +437,58 @@ func (t *table) alter(tgt definition, m *migration) {
 		for _, syn := range target.node.Synonyms {
 			targetSynonyms[syn.Name.Name] = struct{}{}
 		}
-		for syn := range baseSynonyms {
+		for _, syn := range slices.Sorted(maps.Keys(baseSynonyms)) {
 			if _, ok := targetSynonyms[syn]; !ok {
 				ddls = append(ddls, &ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.DropSynonym{Name: &ast.Ident{Name: syn}}})
 			}
 		}
-		for syn := range targetSynonyms {
+		for _, syn := range slices.Sorted(maps.Keys(targetSynonyms)) {
 			if _, ok := baseSynonyms[syn]; !ok {
 				ddls = append(ddls, &ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.AddSynonym{Name: &ast.Ident{Name: syn}}})
 			}
 		}
 	}
+	baseConstraints := make(map[string]*ast.TableConstraint, len(base.node.TableConstraints))
 	if !equalNodes(base.node.TableConstraints, target.node.TableConstraints) {
-		baseConstraints := make(map[string]*ast.TableConstraint, len(base.node.TableConstraints))
+		baseUnnamedConstraints := make(map[string]*ast.TableConstraint)
 		for _, tc := range base.node.TableConstraints {
 			if tc.Name != nil {
 				baseConstraints[tc.Name.Name] = tc
+			} else {
+				baseUnnamedConstraints[tc.Constraint.SQL()] = tc
 			}
 		}
 		targetConstraints := make(map[string]*ast.TableConstraint, len(target.node.TableConstraints))
+		targetUnnamedConstraints := make(map[string]*ast.TableConstraint)
 		for _, tc := range target.node.TableConstraints {
 			if tc.Name != nil {
 				targetConstraints[tc.Name.Name] = tc
+			} else {
+				targetUnnamedConstraints[tc.Constraint.SQL()] = tc
 			}
 		}
-		for name, tc := range targetConstraints {
-			if _, ok := baseConstraints[name]; !ok {
-				ddls = append(ddls, &ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.AddTableConstraint{TableConstraint: tc}})
+		for _, sql := range slices.Sorted(maps.Keys(baseUnnamedConstraints)) {
+			if _, ok := targetUnnamedConstraints[sql]; !ok {
+				// Spanner assigns a name to an unnamed constraint, but we can't know it to drop the constraint.
+				m.fail(fmt.Errorf("cannot drop unnamed constraint on %s: %s: name the constraint in base schema as assigned by Spanner", base.id(), sql))
 			}
 		}
-		for name := range baseConstraints {
-			if _, ok := targetConstraints[name]; !ok {
+		// Drop all constraints first, so that a changed constraint is dropped before it is added again.
+		for _, name := range slices.Sorted(maps.Keys(baseConstraints)) {
+			targetTC, ok := targetConstraints[name]
+			if !ok || !equalNode(baseConstraints[name], targetTC) {
 				ddls = append(ddls, &ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.DropConstraint{Name: &ast.Ident{Name: name}}})
 			}
 		}
-		for name, baseTC := range baseConstraints {
-			if targetTC, ok := targetConstraints[name]; ok {
-				if !equalNode(baseTC, targetTC) {
-					ddls = append(ddls,
-						&ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.DropConstraint{Name: &ast.Ident{Name: targetTC.Name.Name}}},
-						&ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.AddTableConstraint{TableConstraint: targetTC}},
-					)
-				}
+		for _, name := range slices.Sorted(maps.Keys(targetConstraints)) {
+			baseTC, ok := baseConstraints[name]
+			if !ok || !equalNode(baseTC, targetConstraints[name]) {
+				ddls = append(ddls, &ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.AddTableConstraint{TableConstraint: targetConstraints[name]}})
+			}
+		}
+		for _, sql := range slices.Sorted(maps.Keys(targetUnnamedConstraints)) {
+			if _, ok := baseUnnamedConstraints[sql]; !ok {
+				ddls = append(ddls, &ast.AlterTable{Name: target.node.Name, TableAlteration: &ast.AddTableConstraint{TableConstraint: targetUnnamedConstraints[sql]}})
 			}
 		}
 	}
@@ -296,17 +499,184 @@ func (t *table) alter(tgt definition, m *migration) {
 		return
 	}
 
-	m.updateStateIfUndefined(newAlterState(base, target, ddls...))
+	state := newAlterState(base, target, ddls...)
+	for i, op := range state.alters {
+		// Foreign keys must be dropped before and added after the referenced table.
+		switch alt := op.ddl.(*ast.AlterTable).TableAlteration.(type) {
+		case *ast.AddTableConstraint:
+			state.alters[i].dependsOn = append(op.dependsOn, target.constraintReferences(alt.TableConstraint)...)
+		case *ast.DropConstraint:
+			if tc, ok := baseConstraints[alt.Name.Name]; ok {
+				state.alters[i].dependsOn = append(op.dependsOn, base.constraintReferences(tc)...)
+			}
+		}
+	}
+	if rdp := target.node.RowDeletionPolicy; rdp != nil {
+		colID := newColumnID(target.tableID(), rdp.RowDeletionPolicy.ColumnName)
+		if _, ok := base.columns()[colID]; !ok {
+			// The policy references a new column, so attribute the policy to the column to add it after the column.
+			for i, op := range state.alters {
+				switch op.ddl.(*ast.AlterTable).TableAlteration.(type) {
+				case *ast.AddRowDeletionPolicy, *ast.ReplaceRowDeletionPolicy:
+					state.alters[i].id = colID
+					state.alters[i].dependsOn = append(op.dependsOn, target.id())
+				}
+			}
+		}
+	}
+	m.updateStateIfUndefined(state)
+}
+
+// parentTableID returns the table which t is interleaved in.
+func (t *table) parentTableID() optional[tableID] {
+	if t.node.Cluster == nil {
+		return none[tableID]()
+	}
+	return some(newTableIDFromPath(t.node.Cluster.TableName))
 }
 
 func (t *table) dependsOn() []identifier {
+	var ids []identifier
 	if schemaID, ok := t.schemaID().get(); ok {
-		return []identifier{schemaID}
+		ids = append(ids, schemaID)
 	}
-	return nil
+	if lgID, ok := localityGroupIDOf(t.node.Options).get(); ok {
+		ids = append(ids, lgID)
+	}
+	if parentID, ok := t.parentTableID().get(); ok {
+		ids = append(ids, parentID)
+	}
+	return ids
+}
+
+// foreignKeyReferences returns the tables and columns referenced by the foreign keys of t.
+// They are not a part of dependsOn, because tables can reference each other. Only the operations adding or dropping
+// the foreign keys depend on them.
+func (t *table) foreignKeyReferences() []identifier {
+	var ids []identifier
+	for _, tc := range t.node.TableConstraints {
+		ids = append(ids, t.constraintReferences(tc)...)
+	}
+	return ids
+}
+
+// constraintReferences returns the table and columns referenced by the constraint if it is a foreign key.
+func (t *table) constraintReferences(tc *ast.TableConstraint) []identifier {
+	fk, ok := tc.Constraint.(*ast.ForeignKey)
+	if !ok {
+		return nil
+	}
+	refTableID := newTableIDFromPath(fk.ReferenceTable)
+	if refTableID == t.tableID() {
+		return nil
+	}
+	ids := []identifier{refTableID}
+	for _, col := range fk.ReferenceColumns {
+		ids = append(ids, newColumnID(refTableID, col))
+	}
+	return ids
 }
 
-func (t *table) onDependencyChange(me, dependency migrationState, m *migration) {}
+func (t *table) onDependencyChange(me, dependency migrationState, m *migration) {
+	if dependency.kind != migrationKindDropAndAdd {
+		return
+	}
+
+	var refTableID tableID
+	var refColumn optional[string]
+	switch dep := dependency.definition().(type) {
+	case *table:
+		if parentID, ok := t.parentTableID().get(); ok && parentID == dep.tableID() {
+			// An interleaved table can't outlive its parent.
+			if me.kind != migrationKindDrop {
+				m.updateState(me.updateKind(migrationKindDropAndAdd))
+			}
+			return
+		}
+		refTableID = dep.tableID()
+	case *column:
+		refTableID = dep.table.tableID()
+		refColumn = some(dep.node.Name.Name)
+	case *schema, *localityGroup:
+		return
+	default:
+		panic(fmt.Sprintf("unexpected dependOn type on table: %T", dep))
+	}
+
+	base, hasBase := me.base.get()
+	target, hasTarget := me.target.get()
+	if !hasBase || !hasTarget {
+		return
+	}
+	if me.kind == migrationKindUndefined && !equalNode(base.astNode(), target.astNode()) {
+		// Decide the alteration of the table itself before recreating foreign keys.
+		base.alter(target, m)
+		me = m.states[me.id]
+	}
+	switch me.kind {
+	case migrationKindUndefined, migrationKindAlter:
+	default:
+		return
+	}
+
+	baseFKs := make(map[string]*ast.TableConstraint)
+	for _, tc := range base.(*table).node.TableConstraints {
+		if tc.Name != nil {
+			baseFKs[tc.Name.Name] = tc
+		}
+	}
+	// Foreign keys referencing the recreated table or column must be dropped before, and added after the recreation.
+	// Changed foreign keys are already recreated by alter.
+	var ops []operation
+	for _, tc := range target.(*table).node.TableConstraints {
+		fk, ok := tc.Constraint.(*ast.ForeignKey)
+		if !ok || newTableIDFromPath(fk.ReferenceTable) != refTableID {
+			continue
+		}
+		if name, ok := refColumn.get(); ok && !slices.ContainsFunc(fk.ReferenceColumns, func(col *ast.Ident) bool { return col.Name == name }) {
+			continue
+		}
+		if tc.Name == nil {
+			m.fail(fmt.Errorf("cannot recreate unnamed foreign key on %s: %s: name the constraint in base schema as assigned by Spanner", me.id, fk.SQL()))
+			continue
+		}
+		if baseTC, ok := baseFKs[tc.Name.Name]; !ok || !equalNode(baseTC, tc) {
+			continue
+		}
+		if slices.ContainsFunc(me.alters, func(op operation) bool { return alteredConstraint(op.ddl) == tc.Name.Name }) {
+			// The foreign key is already recreated for another referenced column.
+			continue
+		}
+		name := target.(*table).node.Name
+		drop := newOperation(target, operationKindDrop, &ast.AlterTable{Name: name, TableAlteration: &ast.DropConstraint{Name: tc.Name}})
+		add := newOperation(target, operationKindAdd, &ast.AlterTable{Name: name, TableAlteration: &ast.AddTableConstraint{TableConstraint: tc}})
+		refs := target.(*table).constraintReferences(tc)
+		drop.dependsOn = append(drop.dependsOn, refs...)
+		add.dependsOn = append(add.dependsOn, refs...)
+		ops = append(ops, drop, add)
+	}
+	if len(ops) == 0 {
+		return
+	}
+	m.updateState(me.updateKind(migrationKindAlter, append(me.alters, ops...)...))
+}
+
+// alteredConstraint returns the name of the constraint dropped or added by the DDL, or "" if it doesn't.
+func alteredConstraint(ddl ast.DDL) string {
+	at, ok := ddl.(*ast.AlterTable)
+	if !ok {
+		return ""
+	}
+	switch alt := at.TableAlteration.(type) {
+	case *ast.DropConstraint:
+		return alt.Name.Name
+	case *ast.AddTableConstraint:
+		if alt.TableConstraint.Name != nil {
+			return alt.TableConstraint.Name.Name
+		}
+	}
+	return ""
+}
 
 func (t *table) columns() map[columnID]*ast.ColumnDef {
 	m := make(map[columnID]*ast.ColumnDef)
@@ -319,10 +689,21 @@ func (t *table) columns() map[columnID]*ast.ColumnDef {
 type column struct {
 	node  *ast.ColumnDef
 	table *table
+	// rendered caches the SQL of the node, which is compared first for wide tables.
+	rendered optional[string]
 }
 
 func newColumn(table *table, col *ast.ColumnDef) *column {
-	return &column{col, table}
+	return &column{col, table, none[string]()}
+}
+
+func (c *column) sql() string {
+	if sql, ok := c.rendered.get(); ok {
+		return sql
+	}
+	sql := c.node.SQL()
+	c.rendered = some(sql)
+	return sql
 }
 
 func (c *column) id() identifier {
@@ -371,7 +752,26 @@ func (c *column) alter(tgt definition, m *migration) {
 		return
 	}
 
+	_, baseGenerated := base.node.DefaultSemantics.(*ast.GeneratedColumnExpr)
+	_, targetGenerated := target.node.DefaultSemantics.(*ast.GeneratedColumnExpr)
+	if (baseGenerated || targetGenerated) && !equalNode(base.node.DefaultSemantics, target.node.DefaultSemantics) {
+		// The expression of a generated column can't be altered, e.g. TOKENLIST column generated by TOKENIZE_* functions.
+		m.updateStateIfUndefined(newDropAndAddState(base, target))
+		return
+	}
+
+	if !canAlterOptions(base, base.node.Options, target.node.Options) {
+		m.updateStateIfUndefined(newDropAndAddState(base, target))
+		return
+	}
+
 	if equalNode(base.node.Type, target.node.Type) {
+		if _, ok := target.node.Type.(*ast.ArraySchemaType); ok && !base.node.NotNull && target.node.NotNull {
+			// NOT NULL can't be added to ARRAY columns, but can be removed.
+			m.updateStateIfUndefined(newDropAndAddState(base, target))
+			return
+		}
+
 		var ddls []ast.DDL
 		var defaultSet bool
 		if base.node.NotNull != target.node.NotNull {
@@ -392,8 +792,7 @@ func (c *column) alter(tgt definition, m *migration) {
 		}
 
 		if !equalNode(base.node.Options, target.node.Options) {
-			// Need to unset options that are not in the target?
-			ddls = append(ddls, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnSetOptions{Options: target.node.Options}}})
+			ddls = append(ddls, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnSetOptions{Options: optionsToSet(base.node.Options, target.node.Options)}}})
 		}
 
 		if !defaultSet && !equalNode(base.node.DefaultSemantics, target.node.DefaultSemantics) {
@@ -403,30 +802,44 @@ func (c *column) alter(tgt definition, m *migration) {
 				ddls = append(ddls, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnSetDefault{DefaultExpr: defaultExpr}}})
 			}
 		}
-		m.updateStateIfUndefined(newAlterState(base, target, ddls...))
+		m.updateStateIfUndefined(base.alterState(target, ddls...))
 	} else {
-		switch tupleOf(columnTypeOf(base.node.Type), columnTypeOf(target.node.Type)) {
+		switch tupleOf(columnTypeOf(base.node.Type, m.enumTypes), columnTypeOf(target.node.Type, m.enumTypes)) {
 		case tupleOf(scalar{ast.StringTypeName}, scalar{ast.BytesTypeName}),
 			tupleOf(scalar{ast.BytesTypeName}, scalar{ast.StringTypeName}),
 			tupleOf(protoOrEnum{}, scalar{ast.BytesTypeName}),
 			tupleOf(scalar{ast.BytesTypeName}, protoOrEnum{}),
+			tupleOf(protoOrEnum{}, protoOrEnum{}),
+			tupleOf(proto{}, scalar{ast.BytesTypeName}),
+			tupleOf(scalar{ast.BytesTypeName}, proto{}),
+			tupleOf(proto{}, proto{}),
+			tupleOf(enum{}, scalar{ast.Int64TypeName}),
+			tupleOf(scalar{ast.Int64TypeName}, enum{}),
+			tupleOf(enum{}, enum{}),
 			tupleOf(scalar{ast.StringTypeName}, scalar{ast.StringTypeName}),
 			tupleOf(scalar{ast.BytesTypeName}, scalar{ast.BytesTypeName}),
 			tupleOf(array{scalar{ast.StringTypeName}}, array{scalar{ast.StringTypeName}}),
 			tupleOf(array{scalar{ast.BytesTypeName}}, array{scalar{ast.BytesTypeName}}),
-			tupleOf(array{protoOrEnum{}}, array{protoOrEnum{}}):
+			tupleOf(array{protoOrEnum{}}, array{protoOrEnum{}}),
+			tupleOf(array{proto{}}, array{proto{}}),
+			tupleOf(array{enum{}}, array{enum{}}):
 			if target.node.DefaultSemantics == nil {
-				m.updateStateIfUndefined(newAlterState(base, target, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnType{
+				ddls := []ast.DDL{&ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnType{
 					Type:    target.node.Type,
 					NotNull: target.node.NotNull,
-				}}}))
+				}}}}
+				if base.node.DefaultSemantics != nil {
+					// Drop the default explicitly, because changing the type alone does not guarantee the default is removed.
+					ddls = append(ddls, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnDropDefault{}}})
+				}
+				m.updateStateIfUndefined(base.recreateCheckConstraints(target, base.alterState(target, ddls...), m))
 				return
 			} else if defaultExpr, ok := target.node.DefaultSemantics.(*ast.ColumnDefaultExpr); ok {
-				m.updateStateIfUndefined(newAlterState(base, target, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnType{
+				m.updateStateIfUndefined(base.recreateCheckConstraints(target, newAlterState(base, target, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnType{
 					Type:        target.node.Type,
 					NotNull:     target.node.NotNull,
 					DefaultExpr: defaultExpr,
-				}}}))
+				}}}), m))
 				return
 			}
 		default:
@@ -437,8 +850,143 @@ func (c *column) alter(tgt definition, m *migration) {
 	}
 }
 
+// recreateCheckConstraints drops the unchanged check constraints referencing the column before the state changes its type,
+// and adds them after, so that Spanner validates them against the new type.
+// Changed check constraints are already recreated by table.alter.
+func (c *column) recreateCheckConstraints(target *column, state migrationState, m *migration) migrationState {
+	baseChecks := make(map[string]*ast.TableConstraint)
+	for _, tc := range c.table.node.TableConstraints {
+		if tc.Name != nil {
+			baseChecks[tc.Name.Name] = tc
+		}
+	}
+	var drops, adds []operation
+	for _, tc := range target.table.node.TableConstraints {
+		check, ok := tc.Constraint.(*ast.Check)
+		if !ok || !slices.ContainsFunc(columnsInExpr(check.Expr), func(ident *ast.Ident) bool { return ident.Name == target.node.Name.Name }) {
+			continue
+		}
+		if tc.Name == nil {
+			m.fail(fmt.Errorf("cannot recreate unnamed check constraint on %s: %s: name the constraint in base schema as assigned by Spanner", target.table.id(), check.SQL()))
+			continue
+		}
+		if baseTC, ok := baseChecks[tc.Name.Name]; !ok || !equalNode(baseTC, tc) {
+			continue
+		}
+		name := target.table.node.Name
+		drops = append(drops, newOperation(target, operationKindAlter, &ast.AlterTable{Name: name, TableAlteration: &ast.DropConstraint{Name: tc.Name}}))
+		adds = append(adds, newOperation(target, operationKindAlter, &ast.AlterTable{Name: name, TableAlteration: &ast.AddTableConstraint{TableConstraint: tc}}))
+	}
+	if len(drops) == 0 {
+		return state
+	}
+	return state.updateKind(state.kind, slices.Concat(drops, state.alters, adds)...)
+}
+
+// resetSequenceDefault drops the default using the recreated sequence before the sequence is dropped,
+// and sets it again after the sequence is added, because a sequence used by a default can't be dropped.
+func (c *column) resetSequenceDefault(me migrationState, seq *sequence, m *migration) {
+	if me.kind == migrationKindDrop || !me.base.valid || !me.target.valid {
+		return
+	}
+	base := me.base.mustGet().(*column)
+	target := me.target.mustGet().(*column)
+	if !slices.Contains(base.sequenceIDs(), seq.id()) {
+		return
+	}
+	if me.kind == migrationKindUndefined && !equalNode(base.astNode(), target.astNode()) {
+		// Decide the alteration of the column itself before resetting the default.
+		base.alter(target, m)
+		me = m.states[me.id]
+	}
+	switch me.kind {
+	case migrationKindUndefined, migrationKindAlter:
+	default:
+		return
+	}
+
+	hasAlteration := func(f func(ast.ColumnAlteration) bool) bool {
+		return slices.ContainsFunc(me.alters, func(op operation) bool {
+			at, ok := op.ddl.(*ast.AlterTable)
+			if !ok {
+				return false
+			}
+			ac, ok := at.TableAlteration.(*ast.AlterColumn)
+			return ok && f(ac.Alteration)
+		})
+	}
+	var ops []operation
+	if !hasAlteration(func(a ast.ColumnAlteration) bool { _, ok := a.(*ast.AlterColumnDropDefault); return ok }) {
+		drop := newOperation(base, operationKindDrop, &ast.AlterTable{Name: base.table.node.Name, TableAlteration: &ast.AlterColumn{Name: base.node.Name, Alteration: &ast.AlterColumnDropDefault{}}})
+		ops = append(ops, drop)
+	}
+	ops = append(ops, me.alters...)
+	defaultExpr, ok := target.node.DefaultSemantics.(*ast.ColumnDefaultExpr)
+	if ok && !hasAlteration(func(a ast.ColumnAlteration) bool {
+		switch a := a.(type) {
+		case *ast.AlterColumnSetDefault:
+			return true
+		case *ast.AlterColumnType:
+			return a.DefaultExpr != nil
+		}
+		return false
+	}) {
+		ops = append(ops, newOperation(target, operationKindAlter, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AlterColumn{Name: target.node.Name, Alteration: &ast.AlterColumnSetDefault{DefaultExpr: defaultExpr}}}))
+	}
+	m.updateState(me.updateKind(migrationKindAlter, ops...))
+}
+
+// alterState returns the alter state from c to target.
+// Dropping a default using sequences is done with drops, so that it's done before dropping the sequences.
+func (c *column) alterState(target *column, ddls ...ast.DDL) migrationState {
+	state := newAlterState(c, target, ddls...)
+	if len(c.sequenceIDs()) == 0 {
+		return state
+	}
+	for i, op := range state.alters {
+		if ac, ok := op.ddl.(*ast.AlterTable).TableAlteration.(*ast.AlterColumn); ok {
+			if _, ok := ac.Alteration.(*ast.AlterColumnDropDefault); ok {
+				state.alters[i].kind = operationKindDrop
+				state.alters[i].dependsOn = c.dependsOn()
+			}
+		}
+	}
+	return state
+}
+
+// sequenceIDs returns the sequences used by the default value of the column.
+func (c *column) sequenceIDs() []identifier {
+	if c.node.DefaultSemantics == nil {
+		return nil
+	}
+	var ids []identifier
+	ast.Inspect(c.node.DefaultSemantics, func(n ast.Node) bool {
+		if arg, ok := n.(*ast.SequenceArg); ok {
+			switch e := arg.Expr.(type) {
+			case *ast.Ident:
+				ids = append(ids, newSequenceID(&ast.Path{Idents: []*ast.Ident{e}}))
+			case *ast.Path:
+				ids = append(ids, newSequenceID(e))
+			}
+		}
+		return true
+	})
+	return ids
+}
+
 func (c *column) dependsOn() []identifier {
-	return []identifier{c.table.id()}
+	ids := []identifier{c.table.id()}
+	if lgID, ok := localityGroupIDOf(c.node.Options).get(); ok {
+		ids = append(ids, lgID)
+	}
+	if generated, ok := c.node.DefaultSemantics.(*ast.GeneratedColumnExpr); ok {
+		for _, col := range columnsInExpr(generated.Expr) {
+			if col.Name != c.node.Name.Name {
+				ids = append(ids, newColumnID(c.table.tableID(), col))
+			}
+		}
+	}
+	return append(ids, c.sequenceIDs()...)
 }
 
 func (c *column) onDependencyChange(me, dependency migrationState, m *migration) {
@@ -449,6 +997,18 @@ func (c *column) onDependencyChange(me, dependency migrationState, m *migration)
 			// If the table is being added or dropped, the column is also being added or dropped.
 			m.updateState(me.updateKind(migrationKindNone))
 		}
+	case *localityGroup:
+		// Locality group is never recreated, so the reference to it stays valid.
+	case *sequence:
+		// The default using the sequence is dropped by the column's own alter or drop, which are ordered before the sequence drop.
+		if dependency.kind == migrationKindDropAndAdd {
+			c.resetSequenceDefault(me, dep, m)
+		}
+	case *column:
+		// A column referenced by a generated column can't be dropped, so the generated column is recreated too.
+		if dependency.kind == migrationKindDropAndAdd && me.kind != migrationKindDrop && me.base.valid && me.target.valid {
+			m.updateState(me.updateKind(migrationKindDropAndAdd))
+		}
 	default:
 		panic(fmt.Sprintf("unexpected dependOn type on column: %T", dep))
 	}
@@ -528,30 +1088,48 @@ func (i *index) alter(tgt definition, m *migration) {
 				ddls = append(ddls, &ast.AlterIndex{Name: target.node.Name, IndexAlteration: &ast.AddStoredColumn{Name: col}})
 			}
 		}
+		var dropped []columnID
 		for colID, col := range baseStoring {
 			if _, ok := targetStoring[colID]; !ok {
 				ddls = append(ddls, &ast.AlterIndex{Name: target.node.Name, IndexAlteration: &ast.DropStoredColumn{Name: col}})
+				dropped = append(dropped, colID)
 			}
 		}
-		m.updateStateIfUndefined(newAlterState(base, target, ddls...))
+		state := newAlterState(base, target, ddls...)
+		for j, colID := range dropped {
+			// The stored column must be removed from the index before the column is dropped.
+			op := &state.alters[len(state.alters)-len(dropped)+j]
+			op.kind = operationKindDrop
+			op.dependsOn = append(op.dependsOn, colID)
+		}
+		m.updateStateIfUndefined(state)
 		return
 	}
 	m.updateStateIfUndefined(newDropAndAddState(base, target))
 }
 
-func (i *index) dependsOn() []identifier {
+// columnIDs returns the key and stored columns of the index.
+func (i *index) columnIDs() []identifier {
 	var ids []identifier
 	for _, col := range i.node.Keys {
-		ids = append(ids, newColumnID(newTableIDFromPath(i.node.TableName), col.Name))
+		ids = append(ids, newColumnID(i.tableID(), col.Name))
 	}
 	if i.node.Storing != nil {
 		for _, col := range i.node.Storing.Columns {
-			ids = append(ids, newColumnID(newTableIDFromPath(i.node.TableName), col))
+			ids = append(ids, newColumnID(i.tableID(), col))
 		}
 	}
+	return ids
+}
+
+func (i *index) dependsOn() []identifier {
+	ids := i.columnIDs()
 	if schemaID, ok := i.schemaID().get(); ok {
 		ids = append(ids, schemaID)
 	}
+	if i.node.InterleaveIn != nil {
+		ids = append(ids, newTableIDFromIdent(i.node.InterleaveIn.TableName))
+	}
 	ids = append(ids, i.tableID())
 	return ids
 }
@@ -795,6 +1373,7 @@ func (pg *propertyGraph) dependsOn() []identifier {
 				panic(fmt.Sprintf("unexpected property graph type: %T", keys))
 			}
 		}
+		ids = append(ids, propertyGraphElementColumnIDs(tableID, elem)...)
 	}
 	if pg.node.Content.EdgeTables != nil {
 		for _, elem := range pg.node.Content.EdgeTables.Tables.Elements {
@@ -822,11 +1401,41 @@ func (pg *propertyGraph) dependsOn() []identifier {
 					panic(fmt.Sprintf("unexpected property graph type: %T", keys))
 				}
 			}
+			ids = append(ids, propertyGraphElementColumnIDs(tableID, elem)...)
 		}
 	}
 	return ids
 }
 
+// propertyGraphElementColumnIDs returns the columns referenced by the label and property definitions of elem.
+func propertyGraphElementColumnIDs(tableID tableID, elem *ast.PropertyGraphElement) []identifier {
+	var ids []identifier
+	if elem.Properties != nil {
+		ast.Inspect(elem.Properties, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.PropertyGraphDerivedProperty:
+				// The alias is the property name, not a column.
+				for _, col := range columnsInExpr(n.Expr) {
+					ids = append(ids, newColumnID(tableID, col))
+				}
+				return false
+			case *ast.PropertyGraphElementLabelLabelName:
+				return false
+			case *ast.Ident:
+				ids = append(ids, newColumnID(tableID, n))
+			}
+			return true
+		})
+	}
+	if elem.DynamicLabel != nil {
+		ids = append(ids, newColumnID(tableID, elem.DynamicLabel.ColumnName))
+	}
+	if elem.DynamicProperties != nil {
+		ids = append(ids, newColumnID(tableID, elem.DynamicProperties.ColumnName))
+	}
+	return ids
+}
+
 func (pg *propertyGraph) onDependencyChange(me, dependency migrationState, m *migration) {
 	switch me.kind {
 	case migrationKindDrop:
@@ -873,13 +1482,31 @@ func (v *view) alter(tgt definition, m *migration) {
 	base := v
 	target := tgt.(*view)
 
+	for _, id := range base.dependsOn() {
+		if m.kind(id) == migrationKindDrop {
+			// The view must be dropped before the table or view it references, e.g. a table moved to another schema.
+			m.updateStateIfUndefined(newDropAndAddState(base, target))
+			return
+		}
+	}
+
 	targetCopy := *target.node
 	targetCopy.OrReplace = true
 	m.updateStateIfUndefined(newAlterState(base, target, &targetCopy))
 }
 
+func (v *view) schemaID() optional[schemaID] {
+	if len(v.node.Name.Idents) != 2 {
+		return none[schemaID]()
+	}
+	return some(newSchemaID(v.node.Name.Idents[0]))
+}
+
 func (v *view) dependsOn() []identifier {
 	var ids []identifier
+	if schemaID, ok := v.schemaID().get(); ok {
+		ids = append(ids, schemaID)
+	}
 	paths, idents := tablesOrViewsInQueryExpr(v.node.Query)
 	// Can't distinguish between tables and views, so add both.
 	for _, ident := range idents {
@@ -905,7 +1532,7 @@ func (v *view) onDependencyChange(me, dependency migrationState, m *migration) {
 		return
 	}
 	switch dep := dependency.definition().(type) {
-	case *column, *table, *view:
+	case *column, *table, *view, *schema:
 		switch dependency.kind {
 		case migrationKindDropAndAdd:
 			m.updateState(me.updateKind(migrationKindDropAndAdd))
@@ -946,15 +1573,21 @@ func (cs *changeStream) alter(tgt definition, m *migration) {
 	target := tgt.(*changeStream)
 
 	var ddls []ast.DDL
-	if !equalNode(base.node.For, target.node.For) {
+	if !equalChangeStreamFor(base.node.For, target.node.For) {
 		if target.node.For == nil {
 			ddls = append(ddls, &ast.AlterChangeStream{Name: base.node.Name, ChangeStreamAlteration: &ast.ChangeStreamDropForAll{}})
 		} else {
+			// SET FOR replaces the current watch, so FOR ALL doesn't need to be dropped before watching specific tables.
+			// Spanner can't add or remove a single table, so the whole list is set even if only one table is changed.
 			ddls = append(ddls, &ast.AlterChangeStream{Name: target.node.Name, ChangeStreamAlteration: &ast.ChangeStreamSetFor{For: target.node.For}})
 		}
 	}
+	if !canAlterOptions(base, base.node.Options, target.node.Options) {
+		m.updateStateIfUndefined(newDropAndAddState(base, target))
+		return
+	}
 	if !equalNode(base.node.Options, target.node.Options) {
-		ddls = append(ddls, &ast.AlterChangeStream{Name: target.node.Name, ChangeStreamAlteration: &ast.ChangeStreamSetOptions{Options: target.node.Options}})
+		ddls = append(ddls, &ast.AlterChangeStream{Name: target.node.Name, ChangeStreamAlteration: &ast.ChangeStreamSetOptions{Options: optionsToSet(base.node.Options, target.node.Options)}})
 	}
 	if len(ddls) == 0 {
 		return
@@ -962,6 +1595,33 @@ func (cs *changeStream) alter(tgt definition, m *migration) {
 	m.updateStateIfUndefined(newAlterState(base, target, ddls...))
 }
 
+// equalChangeStreamFor reports whether a and b watch the same tables and columns regardless of the order.
+func equalChangeStreamFor(a, b ast.ChangeStreamFor) bool {
+	at, aok := a.(*ast.ChangeStreamForTables)
+	bt, bok := b.(*ast.ChangeStreamForTables)
+	if !aok || !bok {
+		return equalNode(a, b)
+	}
+	watches := func(f *ast.ChangeStreamForTables) []string {
+		var ws []string
+		for _, t := range f.Tables {
+			w := t.TableName.Name
+			if !t.Rparen.Invalid() {
+				// T() watches only the primary key, while T watches all columns.
+				var cols []string
+				for _, c := range t.Columns {
+					cols = append(cols, c.Name)
+				}
+				slices.Sort(cols)
+				w += "(" + strings.Join(cols, ",") + ")"
+			}
+			ws = append(ws, w)
+		}
+		return ws
+	}
+	return equalSet(unique(watches(at)), unique(watches(bt)))
+}
+
 func (cs *changeStream) dependsOn() []identifier {
 	if cs.node.For == nil {
 		return nil
@@ -1045,12 +1705,17 @@ func (s *sequence) alter(tgt definition, m *migration) {
 	base := s
 	target := tgt.(*sequence)
 
-	if !equalNode(base.node.Options, target.node.Options) {
-		m.updateStateIfUndefined(newAlterState(base, target, &ast.AlterSequence{Name: target.node.Name, Options: target.node.Options}))
+	baseCopy := *base.node
+	targetCopy := *target.node
+	baseCopy.Options = nil
+	targetCopy.Options = nil
+	if !equalNode(&baseCopy, &targetCopy) || !canAlterOptions(base, base.node.Options, target.node.Options) {
+		// e.g. sequence_kind can't be altered, so recreate the sequence.
+		m.updateStateIfUndefined(newDropAndAddState(base, target))
 		return
 	}
 
-	panic(fmt.Sprintf("unsupported sequence alternation on: %s", target.node.SQL()))
+	m.updateStateIfUndefined(newAlterState(base, target, &ast.AlterSequence{Name: target.node.Name, Options: target.node.Options}))
 }
 
 func (s *sequence) dependsOn() []identifier {
@@ -1096,7 +1761,7 @@ func (m *model) alter(tgt definition, migration *migration) {
 	targetCopy := *target.node
 	baseCopy.Options = nil
 	targetCopy.Options = nil
-	if equalNode(&baseCopy, &targetCopy) {
+	if equalNode(&baseCopy, &targetCopy) && canAlterOptions(base, base.node.Options, target.node.Options) {
 		migration.updateStateIfUndefined(newAlterState(base, target, &ast.AlterModel{Name: target.node.Name, Options: target.node.Options}))
 		return
 	}
@@ -1458,7 +2123,7 @@ func (g *grant) alter(tgt definition, m *migration) {
 				dropped = append(dropped, &ast.SelectPrivilege{})
 			}
 		}
-		if !slices.Equal(baseSelectColumIDs, targetSelectColumIDs) {
+		if !equalSet(baseSelectColumIDs, targetSelectColumIDs) {
 			var addedColumns, droppedColumns []*ast.Ident
 			for _, colID := range targetSelectColumIDs {
 				if _, ok := baseSelectWithColumn[colID]; !ok {
@@ -1484,7 +2149,7 @@ func (g *grant) alter(tgt definition, m *migration) {
 				dropped = append(dropped, &ast.UpdatePrivilege{})
 			}
 		}
-		if !slices.Equal(baseUpdateColumnIDs, targetUpdateColumnIDs) {
+		if !equalSet(baseUpdateColumnIDs, targetUpdateColumnIDs) {
 			var addedColumns, droppedColumns []*ast.Ident
 			for _, colID := range targetUpdateColumnIDs {
 				if _, ok := baseUpdateWithColumn[colID]; !ok {
@@ -1510,7 +2175,7 @@ func (g *grant) alter(tgt definition, m *migration) {
 				dropped = append(dropped, &ast.InsertPrivilege{})
 			}
 		}
-		if !slices.Equal(baseInsertColumnIDs, targetInsertColumnIDs) {
+		if !equalSet(baseInsertColumnIDs, targetInsertColumnIDs) {
 			var addedColumns, droppedColumns []*ast.Ident
 			for _, colID := range targetInsertColumnIDs {
 				if _, ok := baseInsertWithColumn[colID]; !ok {
@@ -1596,7 +2261,12 @@ func (g *grant) dependsOn() []identifier {
 			ids = append(ids, newChangeStreamID(csName))
 		}
 	case *ast.ExecutePrivilegeOnTableFunction:
-		// none
+		for _, fnName := range p.Names {
+			// The read function of a change stream is named READ_<change stream>.
+			if csName, ok := strings.CutPrefix(fnName.Name, "READ_"); ok {
+				ids = append(ids, newChangeStreamID(&ast.Ident{Name: csName}))
+			}
+		}
 	case *ast.RolePrivilege:
 		for _, roleName := range p.Names {
 			ids = append(ids, newRoleID(roleName))
@@ -1652,8 +2322,135 @@ func (d *database) alter(tgt definition, m *migration) {
 	m.updateStateIfUndefined(newAlterState(base, target, &ast.AlterDatabase{Name: target.node.Name, Options: target.node.Options}))
 }
 
+func (d *database) merge(other definition) bool {
+	oth := other.(*database)
+	// Spanner applies ALTER DATABASE statements in order, so the last option wins.
+	records := slices.Clone(d.node.Options.Records)
+	for _, r := range oth.node.Options.Records {
+		i := slices.IndexFunc(records, func(o *ast.OptionsDef) bool { return o.Name.Name == r.Name.Name })
+		if i >= 0 {
+			records[i] = r
+		} else {
+			records = append(records, r)
+		}
+	}
+	d.node = &ast.AlterDatabase{Name: d.node.Name, Options: &ast.Options{Records: records}}
+	return true
+}
+
 func (d *database) dependsOn() []identifier {
 	return nil
 }
 
 func (d *database) onDependencyChange(me, dependency migrationState, m *migration) {}
+
+type localityGroup struct {
+	node *ast.CreateLocalityGroup
+}
+
+func newLocalityGroup(clg *ast.CreateLocalityGroup) *localityGroup {
+	return &localityGroup{clg}
+}
+
+func (lg *localityGroup) id() identifier {
+	return newLocalityGroupID(lg.node.Name.Name)
+}
+
+func (lg *localityGroup) astNode() ast.Node {
+	return lg.node
+}
+
+func (lg *localityGroup) add() ast.DDL {
+	return lg.node
+}
+
+func (lg *localityGroup) drop() optional[ast.DDL] {
+	return some[ast.DDL](&ast.DropLocalityGroup{
+		Name: lg.node.Name,
+	})
+}
+
+func (lg *localityGroup) alter(tgt definition, m *migration) {
+	base := lg
+	target := tgt.(*localityGroup)
+
+	// A locality group is never recreated, since it can't be dropped while tables or columns refer to it.
+	m.updateStateIfUndefined(newAlterState(base, target, &ast.AlterLocalityGroup{Name: target.node.Name, Options: optionsToSet(base.node.Options, target.node.Options)}))
+}
+
+func (lg *localityGroup) dependsOn() []identifier {
+	return nil
+}
+
+func (lg *localityGroup) onDependencyChange(me, dependency migrationState, m *migration) {}
+
+type customDefinition struct {
+	def  Definition
+	deps []identifier
+}
+
+func newCustomDefinition(def Definition) *customDefinition {
+	return &customDefinition{def, nil}
+}
+
+// resolveDependencies converts the IDs returned by DependsOn to the identifiers of the definitions.
+func (c *customDefinition) resolveDependencies(d *definitions) {
+	ids := make(map[string]identifier, len(d.all))
+	for id := range d.all {
+		ids[id.ID()] = id
+	}
+	c.deps = nil
+	for _, name := range c.def.DependsOn() {
+		if id, ok := ids[name]; ok {
+			c.deps = append(c.deps, id)
+		} else {
+			c.deps = append(c.deps, newCustomID(name))
+		}
+	}
+}
+
+func (c *customDefinition) id() identifier {
+	return newCustomID(c.def.ID())
+}
+
+func (c *customDefinition) astNode() ast.Node {
+	return c.def.Create()
+}
+
+func (c *customDefinition) add() ast.DDL {
+	return c.def.Create()
+}
+
+func (c *customDefinition) drop() optional[ast.DDL] {
+	if ddl := c.def.Drop(); ddl != nil {
+		return some(ddl)
+	}
+	return none[ast.DDL]()
+}
+
+func (c *customDefinition) alter(tgt definition, m *migration) {
+	base := c
+	target := tgt.(*customDefinition)
+
+	ddls := base.def.Alter(target.def)
+	if ddls == nil {
+		m.updateStateIfUndefined(newDropAndAddState(base, target))
+		return
+	}
+	m.updateStateIfUndefined(newAlterState(base, target, ddls...))
+}
+
+func (c *customDefinition) dependsOn() []identifier {
+	return c.deps
+}
+
+func (c *customDefinition) onDependencyChange(me, dependency migrationState, m *migration) {
+	switch me.kind {
+	case migrationKindDrop:
+		return
+	}
+	switch dependency.kind {
+	case migrationKindDropAndAdd:
+		m.updateState(me.updateKind(migrationKindDropAndAdd))
+	}
+}
@@ -0,0 +1,70 @@
+package spannerdiff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDependencyCycle is returned when the generated DDLs can't be ordered due to circular dependencies.
+var ErrDependencyCycle = errors.New("dependency cycle detected")
+
+// ParseError is returned when the base or target SQL can't be parsed.
+type ParseError struct {
+	// Which is "base" or "target".
+	Which string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s SQL: %v", e.Which, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateDefinitionError is returned when the same definition appears more than once in a schema.
+type DuplicateDefinitionError struct {
+	IDs []string
+}
+
+func (e *DuplicateDefinitionError) Error() string {
+	return "duplicated definition found: " + strings.Join(e.IDs, ", ")
+}
+
+// DanglingReferenceError is returned when a definition in target references a definition dropped by the migration.
+type DanglingReferenceError struct {
+	// References is the list of references to dropped definitions, e.g. "Index(IDX1) references Table(T1)".
+	References []string
+}
+
+func (e *DanglingReferenceError) Error() string {
+	return "reference to dropped definition found: " + strings.Join(e.References, ", ")
+}
+
+// UnsupportedDDLError is returned for DDLs not supported by spannerdiff when DiffOption.ErrorOnUnsupportedDDL is set.
+// It is also returned regardless of the option for a schema written in the PostgreSQL dialect.
+type UnsupportedDDLError struct {
+	DDL string
+	// Reason is why the DDL is not supported, if known.
+	Reason string
+}
+
+func (e *UnsupportedDDLError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("unsupported DDL: %s: %s", e.Reason, e.DDL)
+	}
+	return fmt.Sprintf("unsupported DDL: %s", e.DDL)
+}
+
+// TooManyDropsError is returned when the migration has more destructive changes than DiffOption.MaxDrops.
+type TooManyDropsError struct {
+	Max int
+	// Drops is the list of destructive changes, e.g. "drop Table(T1)".
+	Drops []string
+}
+
+func (e *TooManyDropsError) Error() string {
+	return fmt.Sprintf("too many destructive changes: %d exceeds the limit %d: %s", len(e.Drops), e.Max, strings.Join(e.Drops, ", "))
+}
@@ -27,6 +27,8 @@ var _ = []identifier{
 	roleID{},
 	grantID{},
 	databaseID{},
+	localityGroupID{},
+	customID{},
 }
 
 var _ = []struct{}{
@@ -45,6 +47,8 @@ var _ = []struct{}{
 	isComparable(roleID{}),
 	isComparable(grantID{}),
 	isComparable(databaseID{}),
+	isComparable(localityGroupID{}),
+	isComparable(customID{}),
 }
 
 func isComparable[C comparable](_ C) struct{} { return struct{}{} }
@@ -360,3 +364,36 @@ func (i databaseID) ID() string {
 func (i databaseID) String() string {
 	return i.ID()
 }
+
+type localityGroupID struct {
+	name string
+}
+
+func newLocalityGroupID(name string) localityGroupID {
+	return localityGroupID{name}
+}
+
+func (i localityGroupID) ID() string {
+	return fmt.Sprintf("LocalityGroup(%s)", i.name)
+}
+
+func (i localityGroupID) String() string {
+	return i.ID()
+}
+
+// customID is the identifier of a definition registered by RegisterDefinition.
+type customID struct {
+	id string
+}
+
+func newCustomID(id string) customID {
+	return customID{id}
+}
+
+func (i customID) ID() string {
+	return i.id
+}
+
+func (i customID) String() string {
+	return i.ID()
+}
@@ -2,9 +2,9 @@ package spannerdiff
 
 import (
 	"cmp"
-	"errors"
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/cloudspannerecosystem/memefish/ast"
 	"v.io/x/lib/toposort"
@@ -15,10 +15,11 @@ type operation struct {
 	kind      operationKind
 	ddl       ast.DDL
 	dependsOn []identifier
+	comment   string
 }
 
 func newOperation(def definition, kind operationKind, ddl ast.DDL) operation {
-	return operation{def.id(), kind, ddl, def.dependsOn()}
+	return operation{def.id(), kind, ddl, def.dependsOn(), ""}
 }
 
 type operationKind string
@@ -29,10 +30,16 @@ const (
 	operationKindDrop  operationKind = "drop"
 )
 
-func sortOperations(ops []operation) ([]operation, error) {
+func sortOperations(ops []operation, orderBy OrderBy) ([]operation, error) {
 	// sort operations before topological sort to fix the sorted result.
-	slices.SortFunc(ops, func(i, j operation) int {
+	// The sort must be stable to keep the order of multiple operations on the same definition.
+	slices.SortStableFunc(ops, func(i, j operation) int {
+		var byType int
+		if orderBy == OrderByType {
+			byType = cmp.Compare(identifierOrder(i.id), identifierOrder(j.id))
+		}
 		return cmp.Or(
+			byType,
 			cmp.Compare(i.id.ID(), j.id.ID()),
 			cmp.Compare(i.kind, j.kind),
 		)
@@ -74,6 +81,10 @@ func topologicalSort(ops []operation) ([]operation, error) {
 
 	for i := range ops {
 		opPtr := &ops[i]
+		if i > 0 && ops[i-1].id == opPtr.id {
+			// Keep the order of multiple operations on the same definition.
+			s.AddEdge(opPtr, &ops[i-1])
+		}
 		for _, dep := range opPtr.dependsOn {
 			if depPtr, ok := nodeMap[dep]; ok {
 				s.AddEdge(opPtr, depPtr)
@@ -83,7 +94,7 @@ func topologicalSort(ops []operation) ([]operation, error) {
 
 	sorted, cycles := s.Sort()
 	if len(cycles) > 0 {
-		return nil, errors.New("dependency cycle detected")
+		return nil, ErrDependencyCycle
 	}
 
 	result := make([]operation, 0, len(sorted))
@@ -95,8 +106,141 @@ func topologicalSort(ops []operation) ([]operation, error) {
 	return result, nil
 }
 
+// groupByObject reorders operations so that operations on the same object, e.g. a table and its columns, are adjacent where possible.
+// ops must be sorted by sortOperations. Related operations, i.e. operations on the same definition or depending on
+// each other, keep their relative order, so the result is still in dependency order.
+func groupByObject(ops []operation) []operation {
+	related := func(a, b operation) bool {
+		return a.id == b.id || slices.Contains(a.dependsOn, b.id) || slices.Contains(b.dependsOn, a.id)
+	}
+	preds := make([][]int, len(ops))
+	for i := range ops {
+		for j := range i {
+			if related(ops[i], ops[j]) {
+				preds[i] = append(preds[i], j)
+			}
+		}
+	}
+
+	result := make([]operation, 0, len(ops))
+	emitted := make([]bool, len(ops))
+	ready := func(i int) bool {
+		return !emitted[i] && !slices.ContainsFunc(preds[i], func(j int) bool { return !emitted[j] })
+	}
+	var last optional[identifier]
+	for len(result) < len(ops) {
+		// The first operation not emitted yet is always ready because all its predecessors precede it.
+		next := slices.IndexFunc(emitted, func(e bool) bool { return !e })
+		if object, ok := last.get(); ok {
+			for i := next; i < len(ops); i++ {
+				if ready(i) && objectOf(ops[i].id) == object {
+					next = i
+					break
+				}
+			}
+		}
+		emitted[next] = true
+		result = append(result, ops[next])
+		last = some(objectOf(ops[next].id))
+	}
+	return result
+}
+
+// objectOf returns the identifier of the object which id belongs to, e.g. the table of a column.
+func objectOf(id identifier) identifier {
+	if c, ok := id.(columnID); ok {
+		return c.tableID
+	}
+	return id
+}
+
+// splitBatches adds a comment to the first operation of each batch, and returns the number of batches.
+// A new batch is started when a definition dropped in the current batch is added again,
+// so that a name is not dropped and added within a batch. No other batching rule is applied.
+func splitBatches(ops []operation) int {
+	if len(ops) == 0 {
+		return 0
+	}
+	batch := 1
+	dropped := make(map[identifier]struct{})
+	ops[0].comment = fmt.Sprintf("-- batch %d\n", batch) + ops[0].comment
+	for i := range ops {
+		if _, ok := dropped[ops[i].id]; ok && ops[i].kind == operationKindAdd {
+			batch++
+			clear(dropped)
+			ops[i].comment = fmt.Sprintf("-- batch %d\n", batch) + ops[i].comment
+		}
+		if ops[i].kind == operationKindDrop {
+			dropped[ops[i].id] = struct{}{}
+		}
+	}
+	return batch
+}
+
+// deduplicate removes operations whose DDL is the same as the immediately preceding operation.
+// The comments of the removed operations are merged into the kept operation.
+// Non-adjacent duplicates are kept since the operations between them may depend on the first one.
+func deduplicate(ops []operation) []operation {
+	var result []operation
+	for _, op := range ops {
+		if len(result) > 0 {
+			last := &result[len(result)-1]
+			if last.ddl.SQL() == op.ddl.SQL() {
+				if !strings.Contains(last.comment, op.comment) {
+					last.comment += op.comment
+				}
+				continue
+			}
+		}
+		result = append(result, op)
+	}
+	return result
+}
+
 func reverse(ops []operation) {
 	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
 		ops[i], ops[j] = ops[j], ops[i]
 	}
 }
+
+// identifierOrder returns the natural creation order of the definition type.
+func identifierOrder(id identifier) int {
+	switch id.(type) {
+	case databaseID:
+		return 0
+	case schemaID:
+		return 1
+	case protoBundleID:
+		return 2
+	case localityGroupID:
+		return 3
+	case sequenceID:
+		return 4
+	case tableID:
+		return 5
+	case columnID:
+		return 6
+	case indexID:
+		return 7
+	case searchIndexID:
+		return 8
+	case vectorIndexID:
+		return 9
+	case viewID:
+		return 10
+	case propertyGraphID:
+		return 11
+	case changeStreamID:
+		return 12
+	case modelID:
+		return 13
+	case roleID:
+		return 14
+	case grantID:
+		return 15
+	case customID:
+		return 16
+	default:
+		panic(fmt.Sprintf("unexpected identifier type: %T", id))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/alecthomas/chroma/v2"
@@ -46,6 +47,141 @@ func WithSpacer(spacer string, p Printer) Printer {
 	})
 }
 
+func WithLineNumbers(p Printer) Printer {
+	return printerFunc(func(ctx PrintContext, out io.Writer, sql string) error {
+		return p.Print(ctx, out, fmt.Sprintf("-- %d/%d\n", ctx.Index+1, ctx.TotalSQLs)+sql)
+	})
+}
+
+// WithMaxWidth wraps lines longer than width at commas.
+// Commas in quoted strings or identifiers and comment lines are never broken.
+func WithMaxWidth(width int, p Printer) Printer {
+	return printerFunc(func(ctx PrintContext, out io.Writer, sql string) error {
+		lines := strings.Split(sql, "\n")
+		for i, line := range lines {
+			lines[i] = wrapLine(line, width)
+		}
+		return p.Print(ctx, out, strings.Join(lines, "\n"))
+	})
+}
+
+func wrapLine(line string, width int) string {
+	if len(line) <= width || strings.HasPrefix(strings.TrimSpace(line), "--") {
+		return line
+	}
+
+	var segments []string
+	var quote rune
+	var escaped bool
+	start := 0
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case r == '\'', r == '"', r == '`':
+			quote = r
+		case r == ',' && strings.HasPrefix(line[i+1:], " "):
+			segments = append(segments, line[start:i+1])
+			start = i + 2
+		}
+	}
+	segments = append(segments, line[start:])
+
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))] + "  "
+	var b strings.Builder
+	current := segments[0]
+	for _, seg := range segments[1:] {
+		if len(current)+1+len(seg) > width {
+			b.WriteString(current)
+			b.WriteString("\n")
+			current = indent + seg
+		} else {
+			current += " " + seg
+		}
+	}
+	b.WriteString(current)
+	return b.String()
+}
+
+type TypeCase string
+
+const (
+	TypeCaseUpper TypeCase = "upper"
+	TypeCaseLower TypeCase = "lower"
+)
+
+func NewTypeCase(s string) (TypeCase, bool) {
+	switch TypeCase(s) {
+	case TypeCaseUpper, TypeCaseLower:
+		return TypeCase(s), true
+	default:
+		return "", false
+	}
+}
+
+// WithTypeCase recases type names such as INT64 and STRING.
+// Quoted strings or identifiers and comments are left intact.
+// Unquoted identifiers spelled like a type name are recased too, which is harmless as identifiers are case-insensitive.
+func WithTypeCase(c TypeCase, p Printer) Printer {
+	return printerFunc(func(ctx PrintContext, out io.Writer, sql string) error {
+		return p.Print(ctx, out, recaseTypes(sql, c))
+	})
+}
+
+func recaseTypes(sql string, c TypeCase) string {
+	var b strings.Builder
+	var quote rune
+	var escaped, comment bool
+	var word strings.Builder
+	flush := func() {
+		w := word.String()
+		word.Reset()
+		if typeNames[strings.ToUpper(w)] {
+			switch c {
+			case TypeCaseLower:
+				w = strings.ToLower(w)
+			case TypeCaseUpper:
+				w = strings.ToUpper(w)
+			}
+		}
+		b.WriteString(w)
+	}
+	for i, r := range sql {
+		switch {
+		case comment:
+			comment = r != '\n'
+		case escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case r == '\'', r == '"', r == '`':
+			quote = r
+		case r == '-' && strings.HasPrefix(sql[i+1:], "-"):
+			comment = true
+		case r == '_' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z':
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+	return b.String()
+}
+
+var typeNames = map[string]bool{
+	"BOOL": true, "INT64": true, "FLOAT32": true, "FLOAT64": true, "STRING": true, "BYTES": true, "DATE": true,
+	"TIMESTAMP": true, "NUMERIC": true, "JSON": true, "TOKENLIST": true, "ARRAY": true, "STRUCT": true,
+}
+
 type colorPrinter struct {
 	lexer     chroma.Lexer
 	formatter chroma.Formatter
@@ -80,33 +216,91 @@ func NewColorMode(s string) (ColorMode, bool) {
 	}
 }
 
-func DetectTerminalPrinter(mode ColorMode, stdout *os.File) Printer {
+// PrinterOptions customizes the printer created by NewPrinter.
+type PrinterOptions struct {
+	// Style is a chroma XML style. DefaultStyle is used if empty.
+	Style string
+	// Formatter is a chroma formatter name such as "terminal256". Detected from COLORTERM and TERM if empty.
+	Formatter string
+	// Spacer is written between statements. No spacer is written if empty.
+	Spacer string
+}
+
+// DefaultPrinterOptions returns the options used by DetectTerminalPrinter.
+func DefaultPrinterOptions() PrinterOptions {
+	return PrinterOptions{
+		Style:  DefaultStyle,
+		Spacer: "\n",
+	}
+}
+
+func NewPrinter(mode ColorMode, stdout *os.File, opts PrinterOptions) (Printer, error) {
 	var p Printer
 	switch mode {
 	case ColorAlways:
-		p = NewColorTerminalPrinter()
+		cp, err := NewColorPrinter(opts)
+		if err != nil {
+			return nil, err
+		}
+		p = cp
 	case ColorNever:
 		p = NoStylePrinter{}
 	case ColorAuto:
 		if isatty.IsTerminal(stdout.Fd()) {
-			p = NewColorTerminalPrinter()
+			cp, err := NewColorPrinter(opts)
+			if err != nil {
+				return nil, err
+			}
+			p = cp
 		} else {
 			p = NoStylePrinter{}
 		}
 	default:
-		panic(fmt.Sprintf("unexpected color mode: %s", mode)) // パニックではなくエラーを返すように変更も検討すべき
+		return nil, fmt.Errorf("unexpected color mode: %s", mode)
 	}
-	return WithSpacer("\n", p)
+	if opts.Spacer != "" {
+		p = WithSpacer(opts.Spacer, p)
+	}
+	return p, nil
+}
+
+func DetectTerminalPrinter(mode ColorMode, stdout *os.File) Printer {
+	p, err := NewPrinter(mode, stdout, DefaultPrinterOptions())
+	if err != nil {
+		panic(err.Error()) // パニックではなくエラーを返すように変更も検討すべき
+	}
+	return p
 }
 
 func NewColorTerminalPrinter() Printer {
-	lexer := lexers.Get("sql")
-	formatter := formatters.Get(detectColorFormatter())
-	style, err := chroma.NewXMLStyle(strings.NewReader(defaultStyle))
+	p, err := NewColorPrinter(PrinterOptions{})
 	if err != nil {
 		panic(fmt.Sprintf("failed to load default style: %v", err))
 	}
-	return colorPrinter{lexer, formatter, style}
+	return p
+}
+
+// NewColorPrinter returns a printer colorizing DDLs with the style and the formatter of opts.
+// opts.Spacer is not used.
+func NewColorPrinter(opts PrinterOptions) (Printer, error) {
+	lexer := lexers.Get("sql")
+	formatterName := opts.Formatter
+	if formatterName == "" {
+		formatterName = detectColorFormatter()
+	}
+	formatter, ok := formatters.Registry[formatterName]
+	if !ok {
+		return nil, fmt.Errorf("unknown formatter: %s", formatterName)
+	}
+	styleXML := opts.Style
+	if styleXML == "" {
+		styleXML = DefaultStyle
+	}
+	style, err := chroma.NewXMLStyle(strings.NewReader(styleXML))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load style: %w", err)
+	}
+	return colorPrinter{lexer, formatter, style}, nil
 }
 
 func detectColorFormatter() string {
@@ -127,7 +321,8 @@ func detectColorFormatter() string {
 	}
 }
 
-const defaultStyle = `
+// DefaultStyle is the chroma XML style used by default.
+const DefaultStyle = `
 <style name="default">
   <entry type="Keyword" style="bold #4482d1"/>
   <entry type="KeywordType" style="bold #3c9dd0"/>
@@ -139,17 +334,63 @@ const defaultStyle = `
 </style>
 `
 
+// LightStyle is a chroma XML style for terminals with a light background.
+const LightStyle = `
+<style name="light">
+  <entry type="Keyword" style="bold #1f4e99"/>
+  <entry type="KeywordType" style="bold #006a8e"/>
+  <entry type="LiteralString" style="#2e7d32"/>
+  <entry type="LiteralNumber" style="#8d6e00"/>
+  <entry type="GenericInserted" style="bold #1b5e20"/> <!-- CREATE -->
+  <entry type="GenericEmph" style="bold #e65100"/> <!-- ALTER -->
+  <entry type="GenericDeleted" style="bold #b71c1c"/> <!-- DROP -->
+</style>
+`
+
+// MonochromeBoldStyle is a chroma XML style emphasizing keywords without colors.
+const MonochromeBoldStyle = `
+<style name="monochrome-bold">
+  <entry type="Keyword" style="bold"/>
+  <entry type="KeywordType" style="bold"/>
+  <entry type="GenericInserted" style="bold underline"/> <!-- CREATE -->
+  <entry type="GenericEmph" style="bold underline"/> <!-- ALTER -->
+  <entry type="GenericDeleted" style="bold underline"/> <!-- DROP -->
+</style>
+`
+
+var colorThemes = map[string]string{
+	"default":         DefaultStyle,
+	"light":           LightStyle,
+	"monochrome-bold": MonochromeBoldStyle,
+}
+
+// ColorThemeStyle returns the chroma XML style of the built-in color theme.
+func ColorThemeStyle(name string) (string, bool) {
+	style, ok := colorThemes[name]
+	return style, ok
+}
+
+// ColorThemeNames returns the names of the built-in color themes.
+func ColorThemeNames() []string {
+	names := make([]string, 0, len(colorThemes))
+	for name := range colorThemes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
 func wrapIterator(iter chroma.Iterator) chroma.Iterator {
 	return func() chroma.Token {
 		t := iter()
-		switch strings.ToUpper(t.Value) {
-		case "BOOL", "INT64", "FLOAT32", "FLOAT64", "STRING", "BYTES", "DATE", "TIMESTAMP", "NUMERIC", "JSON", "TOKENLIST", "ARRAY", "STRUCT":
+		switch v := strings.ToUpper(t.Value); {
+		case typeNames[v]:
 			t.Type = chroma.KeywordType
-		case "CREATE", "ADD":
+		case v == "CREATE", v == "ADD":
 			t.Type = chroma.GenericInserted // fake type for colorize
-		case "ALTER", "REPLACE":
+		case v == "ALTER", v == "REPLACE":
 			t.Type = chroma.GenericEmph // fake type for colorize
-		case "DROP", "DELETE":
+		case v == "DROP", v == "DELETE":
 			t.Type = chroma.GenericDeleted // fake type for colorize
 		default:
 			if token.IsKeyword(t.Value) {
@@ -1,8 +1,14 @@
 package spannerdiff
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cloudspannerecosystem/memefish"
 	"github.com/cloudspannerecosystem/memefish/ast"
@@ -11,54 +17,493 @@ import (
 type DiffOption struct {
 	ErrorOnUnsupportedDDL bool
 	Printer               Printer
+	// SchemaRename maps schema names in base to schema names in target.
+	// Objects moved from the old schema to the new schema are reported in DiffResult.Warnings.
+	// Spanner cannot move objects between schemas, so they are still dropped from the old schema and created in the new schema.
+	SchemaRename map[string]string
+	// QualifySchema prefixes unqualified names of tables, indexes and views in the output with the schema.
+	// Table names in view queries are not qualified.
+	QualifySchema string
+	// DefaultSchema is the name of the default schema of the database.
+	// Names of tables, indexes, views and sequences qualified by the default schema are compared as unqualified names, e.g. S.T1 as T1.
+	// Names in view queries and expressions are not normalized.
+	DefaultSchema string
+	// OrderBy decides how independent statements are ordered. Default is OrderByID.
+	OrderBy OrderBy
+	// Ignore is the list of glob patterns matched against identifiers such as "Table(T1)" or "Table(T1):Column(C1)".
+	// Matched definitions and definitions depending on them are excluded from both base and target.
+	Ignore []string
+	// AnnotateDrops adds a comment above each DROP statement listing dependent definitions dropped together.
+	AnnotateDrops bool
+	// Hints adds a comment above each statement losing data, explaining what is lost and how to keep it.
+	Hints bool
+	// WarnDestructive reports migrations losing data, such as dropping a table, recreating a sequence or replacing a model, in DiffResult.Warnings.
+	WarnDestructive bool
+	// WarnUndefinedReferences reports indexes, views and grants referencing definitions not in the schema in DiffResult.Warnings.
+	WarnUndefinedReferences bool
+	// SafeOnly skips destructive migrations (drop and recreate), and emits only additions and in-place alterations.
+	SafeOnly bool
+	// MaxDrops fails Diff with TooManyDropsError if the migration has more destructive changes (drops and recreates) than the value.
+	// There is no limit if nil.
+	MaxDrops *int
+	// AdditiveOnly skips all migrations removing something from the schema, such as drops, revokes and dropping constraints.
+	// The schema after the migration may not match the target.
+	AdditiveOnly bool
+	// TypeCase recases type names such as INT64 in the output. Type names are kept as rendered if empty.
+	TypeCase TypeCase
+	// SplitBatches starts a new batch of statements where a definition dropped in the current batch is added again,
+	// and adds a comment above the first statement of each batch. No other Spanner limits on a single schema update,
+	// such as the number of statements, are considered.
+	SplitBatches bool
+	// SafeTypeChange changes the type of a column, which can't be altered in place, by adding a temporary column
+	// instead of dropping and adding the column. A hint to backfill the temporary column is added as a comment.
+	// The temporary column is named <column>_new. Spanner can't rename columns, so the schema doesn't converge to
+	// the target: the next diff drops <column>_new and adds <column> again.
+	// A column referenced by indexes, constraints or generated columns is recreated as usual, and reported in DiffResult.Warnings.
+	SafeTypeChange bool
+	// EnumTypes is the list of the full names of the named types which are ENUMs, e.g. "examples.music.Genre".
+	// The other named types are PROTOs. A column type changed between a PROTO and an ENUM is recreated.
+	// If empty, PROTOs and ENUMs are not distinguished because the proto descriptors are not part of the schema.
+	EnumTypes []string
+	// IdempotentGrants emits a REVOKE before each GRANT so that a partially applied migration can be re-run.
+	IdempotentGrants bool
+	// GroupByObject places statements on the same object, e.g. a table and its columns, next to each other
+	// as far as the dependency order allows.
+	GroupByObject bool
+	// Deduplicate removes statements identical to the immediately preceding statement. Their comments are merged.
+	// Only adjacent duplicates are removed; a duplicate separated by other statements is kept, because the
+	// statements in between may depend on it. Duplicates can occur when custom definitions registered by
+	// RegisterDefinition generate the same DDL.
+	Deduplicate bool
+	// WarnWriter receives each of DiffResult.Warnings as a line prefixed with "warning: ", separately from the DDL output.
+	// Warnings are only returned in DiffResult if nil.
+	WarnWriter io.Writer
+	// Profile receives the elapsed time of each phase of Diff if not nil.
+	Profile io.Writer
+	// Range selects statements to print from the generated statements. All statements are printed if nil.
+	Range *StatementRange
+	// FinalNewline decides how the output ends. Default is FinalNewlineOne.
+	FinalNewline FinalNewline
+}
+
+type OrderBy string
+
+const (
+	// OrderByID orders statements by identifier of the definition.
+	OrderByID OrderBy = "id"
+	// OrderByType groups statements by type of the definition, e.g. tables, then indexes, then views.
+	OrderByType OrderBy = "type"
+)
+
+func NewOrderBy(s string) (OrderBy, bool) {
+	switch OrderBy(s) {
+	case OrderByID, OrderByType:
+		return OrderBy(s), true
+	default:
+		return "", false
+	}
+}
+
+type FinalNewline string
+
+const (
+	// FinalNewlineOne ends the output with a newline after the last statement.
+	FinalNewlineOne FinalNewline = "one"
+	// FinalNewlineNone ends the output with the semicolon of the last statement.
+	FinalNewlineNone FinalNewline = "none"
+)
+
+func NewFinalNewline(s string) (FinalNewline, bool) {
+	switch FinalNewline(s) {
+	case FinalNewlineOne, FinalNewlineNone:
+		return FinalNewline(s), true
+	default:
+		return "", false
+	}
+}
+
+// StatementRange is a 1-based inclusive range of statements, e.g. 1:10 for the first 10 statements.
+type StatementRange struct {
+	From int
+	To   int
+}
+
+// ParseStatementRange parses a range in the form of "N:M".
+func ParseStatementRange(s string) (StatementRange, error) {
+	from, to, ok := strings.Cut(s, ":")
+	if !ok {
+		return StatementRange{}, fmt.Errorf("invalid statement range: %s: must be N:M", s)
+	}
+	var r StatementRange
+	var err error
+	if r.From, err = strconv.Atoi(from); err != nil {
+		return StatementRange{}, fmt.Errorf("invalid statement range: %s: %w", s, err)
+	}
+	if r.To, err = strconv.Atoi(to); err != nil {
+		return StatementRange{}, fmt.Errorf("invalid statement range: %s: %w", s, err)
+	}
+	if r.From < 1 || r.From > r.To {
+		return StatementRange{}, fmt.Errorf("invalid statement range: %s: must be 1 <= N <= M", s)
+	}
+	return r, nil
+}
+
+// DiffResult is the summary of the migration generated by DiffWithResult.
+type DiffResult struct {
+	// Statements is the number of generated DDL statements, including ones not printed by DiffOption.Range.
+	Statements int
+	// EmptySchemas reports whether both base and target have no definitions.
+	EmptySchemas bool
+	// Skipped is the list of destructive migrations skipped by DiffOption.SafeOnly or DiffOption.AdditiveOnly, e.g. "drop Table(T1)",
+	// and the statements removing something skipped from the other migrations by DiffOption.AdditiveOnly,
+	// e.g. "alter Table(T1): ALTER TABLE T1 DROP ROW DELETION POLICY".
+	Skipped []string
+	// Warnings is the list of migrations losing data, reported when DiffOption.WarnDestructive is set,
+	// and columns recreated despite DiffOption.SafeTypeChange, followed by undefined references reported when DiffOption.WarnUndefinedReferences is set,
+	// and objects moved to another schema reported when DiffOption.SchemaRename is set.
+	Warnings []string
+	// Batches is the number of batches when DiffOption.SplitBatches is set.
+	Batches int
+}
+
+type profiler struct {
+	w    io.Writer
+	last time.Time
+}
+
+func newProfiler(w io.Writer) *profiler {
+	return &profiler{w, time.Now()}
+}
+
+// record writes the elapsed time since the last record as the phase.
+func (p *profiler) record(phase string) {
+	if p.w == nil {
+		return
+	}
+	now := time.Now()
+	_, _ = fmt.Fprintf(p.w, "%s: %s\n", phase, now.Sub(p.last))
+	p.last = now
+}
+
+// Dump writes the DDL creating each definition of the schema in dependency order.
+// It shows how the schema is interpreted, e.g. grants merged into a statement per grantee and object.
+func Dump(schemaSQL io.Reader, output io.Writer, option DiffOption) error {
+	return Diff(strings.NewReader(""), schemaSQL, output, option)
 }
 
 func Diff(baseSQL, targetSQL io.Reader, output io.Writer, option DiffOption) error {
+	_, err := DiffWithResult(baseSQL, targetSQL, output, option)
+	return err
+}
+
+// DiffWithResult is the same as Diff, but also returns the summary of the migration,
+// such as the number of statements and the warnings.
+func DiffWithResult(baseSQL, targetSQL io.Reader, output io.Writer, option DiffOption) (DiffResult, error) {
+	ops, result, err := plan(baseSQL, targetSQL, option)
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	printer := option.Printer
+	if printer == nil {
+		printer = NoStylePrinter{}
+	}
+	if option.TypeCase != "" {
+		printer = WithTypeCase(option.TypeCase, printer)
+	}
+
+	ctx := PrintContext{TotalSQLs: len(ops)}
+	for i, op := range ops {
+		ctx.Index = i
+		sql := op.comment + op.ddl.SQL() + ";\n"
+		if option.FinalNewline == FinalNewlineNone && i == len(ops)-1 {
+			sql = strings.TrimSuffix(sql, "\n")
+		}
+		if err := printer.Print(ctx, output, sql); err != nil {
+			return DiffResult{}, fmt.Errorf("failed to write migration DDL: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// PlanDDLs returns the migration DDL statements without trailing semicolons, as UpdateDatabaseDdl takes them.
+// Options only affecting the output, such as DiffOption.Printer and DiffOption.TypeCase, are ignored.
+func PlanDDLs(baseSQL, targetSQL io.Reader, option DiffOption) ([]string, DiffResult, error) {
+	ops, result, err := plan(baseSQL, targetSQL, option)
+	if err != nil {
+		return nil, DiffResult{}, err
+	}
+	ddls := make([]string, 0, len(ops))
+	for _, op := range ops {
+		ddls = append(ddls, op.ddl.SQL())
+	}
+	return ddls, result, nil
+}
+
+// PlannedOperation is a migration statement with its metadata.
+type PlannedOperation struct {
+	// Kind is one of "add", "alter" and "drop".
+	Kind string `json:"kind"`
+	// ID is the identifier of the definition changed by the statement, e.g. "Table(T1)".
+	ID string `json:"id"`
+	// DDL is the statement without the trailing semicolon.
+	DDL string `json:"ddl"`
+	// DependsOn is the identifiers of the definitions the changed definition depends on.
+	DependsOn []string `json:"dependsOn"`
+}
+
+// PlanOperations returns the migration statements with their metadata, so that the apply order can be decided by the caller.
+// Options only affecting the output, such as DiffOption.Printer and DiffOption.TypeCase, are ignored.
+func PlanOperations(baseSQL, targetSQL io.Reader, option DiffOption) ([]PlannedOperation, DiffResult, error) {
+	ops, result, err := plan(baseSQL, targetSQL, option)
+	if err != nil {
+		return nil, DiffResult{}, err
+	}
+	planned := make([]PlannedOperation, 0, len(ops))
+	for _, op := range ops {
+		dependsOn := []string{}
+		for _, id := range unique(op.dependsOn) {
+			dependsOn = append(dependsOn, id.String())
+		}
+		planned = append(planned, PlannedOperation{
+			Kind:      string(op.kind),
+			ID:        op.id.String(),
+			DDL:       op.ddl.SQL(),
+			DependsOn: dependsOn,
+		})
+	}
+	return planned, result, nil
+}
+
+// plan returns the sorted operations selected by DiffOption.Range.
+func plan(baseSQL, targetSQL io.Reader, option DiffOption) ([]operation, DiffResult, error) {
+	prof := newProfiler(option.Profile)
+
 	base, err := io.ReadAll(baseSQL)
 	if err != nil {
-		return fmt.Errorf("failed to read base SQL: %w", err)
+		return nil, DiffResult{}, fmt.Errorf("failed to read base SQL: %w", err)
 	}
 	target, err := io.ReadAll(targetSQL)
 	if err != nil {
-		return fmt.Errorf("failed to read target SQL: %w", err)
+		return nil, DiffResult{}, fmt.Errorf("failed to read target SQL: %w", err)
 	}
 
-	baseDDLs, err := memefish.ParseDDLs("base", string(base))
+	baseDDLs, err := parseDDLs("base", string(base))
 	if err != nil {
-		return fmt.Errorf("failed to parse base SQL: %w", err)
+		return nil, DiffResult{}, err
 	}
-	targetDDLs, err := memefish.ParseDDLs("target", string(target))
+	targetDDLs, err := parseDDLs("target", string(target))
 	if err != nil {
-		return fmt.Errorf("failed to parse target SQL: %w", err)
+		return nil, DiffResult{}, err
+	}
+	prof.record("parse")
+
+	if option.DefaultSchema != "" {
+		unqualifyDefaultSchema(baseDDLs, option.DefaultSchema)
+		unqualifyDefaultSchema(targetDDLs, option.DefaultSchema)
 	}
 
 	baseDefs, err := newDefinitions(baseDDLs, option.ErrorOnUnsupportedDDL)
 	if err != nil {
-		return err
+		return nil, DiffResult{}, err
 	}
 	targetDefs, err := newDefinitions(targetDDLs, option.ErrorOnUnsupportedDDL)
 	if err != nil {
-		return err
+		return nil, DiffResult{}, err
+	}
+
+	if len(option.Ignore) > 0 {
+		if err := baseDefs.ignore(option.Ignore); err != nil {
+			return nil, DiffResult{}, err
+		}
+		if err := targetDefs.ignore(option.Ignore); err != nil {
+			return nil, DiffResult{}, err
+		}
+	}
+	prof.record("definitions")
+
+	// A definition kept in target can't reference a definition dropped by the migration.
+	var dangling []string
+	for _, ref := range targetDefs.undefinedReferences() {
+		if _, ok := baseDefs.all[ref.to]; ok {
+			dangling = append(dangling, fmt.Sprintf("%s references %s", ref.from, ref.to))
+		}
+	}
+	if len(dangling) > 0 {
+		return nil, DiffResult{}, &DanglingReferenceError{dangling}
 	}
 
-	stmts, err := diffDefinitions(baseDefs, targetDefs)
+	ops, result, err := diffDefinitions(baseDefs, targetDefs, option)
 	if err != nil {
-		return err
+		return nil, DiffResult{}, err
 	}
+	if option.WarnUndefinedReferences {
+		for _, ref := range baseDefs.undefinedReferences() {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("base: %s references undefined %s", ref.from, ref.to))
+		}
+		for _, ref := range targetDefs.undefinedReferences() {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("target: %s references undefined %s", ref.from, ref.to))
+		}
+	}
+	if len(option.SchemaRename) > 0 {
+		moved, err := schemaMoves(string(base), baseDefs, targetDefs, option)
+		if err != nil {
+			return nil, DiffResult{}, err
+		}
+		result.Warnings = append(result.Warnings, moved...)
+	}
+	prof.record("diff")
 
-	printer := option.Printer
-	if printer == nil {
-		printer = NoStylePrinter{}
+	ops, err = sortOperations(ops, option.OrderBy)
+	if err != nil {
+		return nil, DiffResult{}, err
 	}
-	ctx := PrintContext{TotalSQLs: len(stmts)}
-	for i, stmt := range stmts {
-		ctx.Index = i
-		if err := printer.Print(ctx, output, stmt.SQL()+";\n"); err != nil {
-			return fmt.Errorf("failed to write migration DDL: %w", err)
+	if option.QualifySchema != "" {
+		ddls := make([]ast.DDL, 0, len(ops))
+		for _, op := range ops {
+			ddls = append(ddls, op.ddl)
 		}
+		qualifySchema(ddls, option.QualifySchema)
 	}
+	if option.GroupByObject {
+		ops = groupByObject(ops)
+	}
+	if option.Deduplicate {
+		ops = deduplicate(ops)
+	}
+	if option.SplitBatches {
+		result.Batches = splitBatches(ops)
+	}
+	prof.record("sort")
 
-	return nil
+	result.Statements = len(ops)
+	if r := option.Range; r != nil {
+		if r.From < 1 || r.From > r.To || r.To > len(ops) {
+			return nil, DiffResult{}, fmt.Errorf("statement range %d:%d is out of the statements 1:%d", r.From, r.To, len(ops))
+		}
+		ops = ops[r.From-1 : r.To]
+	}
+	result.EmptySchemas = len(baseDefs.all) == 0 && len(targetDefs.all) == 0
+	if option.WarnWriter != nil {
+		for _, w := range result.Warnings {
+			if _, err := fmt.Fprintf(option.WarnWriter, "warning: %s\n", w); err != nil {
+				return nil, DiffResult{}, fmt.Errorf("failed to write warning: %w", err)
+			}
+		}
+	}
+	return ops, result, nil
+}
+
+// parseDDLs parses the SQL of which, "base" or "target".
+// A schema of the PostgreSQL dialect, which the parser doesn't support, is reported as UnsupportedDDLError instead of ParseError.
+func parseDDLs(which, sql string) ([]ast.DDL, error) {
+	ddls, err := memefish.ParseDDLs(which, sql)
+	if err != nil {
+		if stmt, ok := postgreSQLStatement(sql); ok {
+			return nil, &UnsupportedDDLError{DDL: stmt, Reason: "the PostgreSQL dialect is not supported"}
+		}
+		return nil, &ParseError{which, err}
+	}
+	return ddls, nil
+}
+
+// schemaMoves returns warnings for the definitions moved to another schema by DiffOption.SchemaRename.
+// A definition is moved if it is not in base but in target after renaming the schema of base.
+func schemaMoves(baseSQL string, baseDefs, targetDefs *definitions, option DiffOption) ([]string, error) {
+	var warnings []string
+	for _, from := range slices.Sorted(maps.Keys(option.SchemaRename)) {
+		to := option.SchemaRename[from]
+		// The base is parsed again so that the statements of the migration keep the original names.
+		ddls, err := memefish.ParseDDLs("base", baseSQL)
+		if err != nil {
+			return nil, &ParseError{"base", err}
+		}
+		renameSchema(ddls, from, to)
+		if option.DefaultSchema != "" {
+			unqualifyDefaultSchema(ddls, option.DefaultSchema)
+		}
+		renamed, err := newDefinitions(ddls, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(option.Ignore) > 0 {
+			if err := renamed.ignore(option.Ignore); err != nil {
+				return nil, err
+			}
+		}
+		for id := range renamed.all {
+			if _, ok := id.(columnID); ok {
+				// Columns are moved with the table.
+				continue
+			}
+			_, inBase := baseDefs.all[id]
+			_, inTarget := targetDefs.all[id]
+			if !inBase && inTarget {
+				warnings = append(warnings, fmt.Sprintf("%s is moved from schema %s, but Spanner can't move objects between schemas, so it is dropped and created again", id, from))
+			}
+		}
+	}
+	slices.Sort(warnings)
+	return warnings, nil
+}
+
+// RenderCreate returns the DDL creating the definition identified by id, such as "Table(T1)" or "Table(T1):Column(C1)".
+func RenderCreate(ddls []ast.DDL, id string) (string, error) {
+	defs, err := newDefinitions(ddls, false)
+	if err != nil {
+		return "", err
+	}
+	for defID, def := range defs.all {
+		if defID.ID() == id {
+			return def.add().SQL(), nil
+		}
+	}
+	return "", fmt.Errorf("definition not found: %s", id)
+}
+
+// DroppedObjects returns the identifiers of the definitions in base but not in target, such as "Table(T1)", in sorted order.
+// Definitions recreated by the migration are not included.
+func DroppedObjects(baseSQL, targetSQL io.Reader) ([]string, error) {
+	var defs [2]*definitions
+	for i, in := range []struct {
+		name string
+		r    io.Reader
+	}{{"base", baseSQL}, {"target", targetSQL}} {
+		sql, err := io.ReadAll(in.r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s SQL: %w", in.name, err)
+		}
+		ddls, err := parseDDLs(in.name, string(sql))
+		if err != nil {
+			return nil, err
+		}
+		defs[i], err = newDefinitions(ddls, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Same as migration.drops, but columns of a dropped table are also reported.
+	var dropped []string
+	for id := range defs[0].all {
+		if _, ok := defs[1].all[id]; !ok {
+			dropped = append(dropped, id.String())
+		}
+	}
+	slices.Sort(dropped)
+	return dropped, nil
+}
+
+// UnsupportedStatements returns the statements ignored by Diff because they are not supported.
+func UnsupportedStatements(ddls []ast.DDL) []ast.DDL {
+	var unsupported []ast.DDL
+	for _, ddl := range ddls {
+		if _, ok := definitionsOf(ddl); !ok {
+			unsupported = append(unsupported, ddl)
+		}
+	}
+	return unsupported
 }
 
 type migrationKind string
@@ -119,20 +564,23 @@ func (ms migrationState) updateKind(kind migrationKind, alters ...operation) mig
 func (ms migrationState) operations() []operation {
 	switch ms.kind {
 	case migrationKindAdd:
-		return []operation{newOperation(ms.target.mustGet(), operationKindAdd, ms.target.mustGet().add())}
+		return []operation{newDefinitionOperation(ms.target.mustGet(), operationKindAdd, ms.target.mustGet().add())}
 	case migrationKindAlter:
 		return ms.alters
 	case migrationKindDrop:
 		if ddl, ok := ms.base.mustGet().drop().get(); ok {
-			return []operation{newOperation(ms.base.mustGet(), operationKindDrop, ddl)}
+			return []operation{newDefinitionOperation(ms.base.mustGet(), operationKindDrop, ddl)}
 		}
 		return nil
 	case migrationKindDropAndAdd:
 		var alters []operation
-		if ddl, ok := ms.base.mustGet().drop().get(); ok {
-			alters = append(alters, newOperation(ms.base.mustGet(), operationKindDrop, ddl))
+		// The base may not exist when a new definition depends on a recreated one.
+		if base, ok := ms.base.get(); ok {
+			if ddl, ok := base.drop().get(); ok {
+				alters = append(alters, newDefinitionOperation(base, operationKindDrop, ddl))
+			}
 		}
-		alters = append(alters, newOperation(ms.target.mustGet(), operationKindAdd, ms.target.mustGet().add()))
+		alters = append(alters, newDefinitionOperation(ms.target.mustGet(), operationKindAdd, ms.target.mustGet().add()))
 		return alters
 	case migrationKindNone, migrationKindUndefined:
 		return nil
@@ -141,6 +589,56 @@ func (ms migrationState) operations() []operation {
 	}
 }
 
+// newDefinitionOperation returns the operation creating or dropping the whole definition.
+// Creating or dropping a table also adds or drops its foreign keys, so the operation depends on the referenced tables.
+func newDefinitionOperation(def definition, kind operationKind, ddl ast.DDL) operation {
+	op := newOperation(def, kind, ddl)
+	if t, ok := def.(*table); ok {
+		op.dependsOn = append(op.dependsOn, t.foreignKeyReferences()...)
+	}
+	return op
+}
+
+func (ms migrationState) isDestructive() bool {
+	switch ms.kind {
+	case migrationKindDrop:
+		return true
+	case migrationKindDropAndAdd:
+		return ms.base.valid
+	default:
+		return false
+	}
+}
+
+// dataLossWarning returns a warning if the migration loses data stored in the definition.
+func (ms migrationState) dataLossWarning() optional[string] {
+	if ms.kind == migrationKindAlter {
+		// Spanner can't alter the columns of a model, so the model is replaced.
+		for _, op := range ms.alters {
+			if _, ok := op.ddl.(*ast.CreateModel); ok {
+				return some(fmt.Sprintf("%s is replaced to change its columns", ms.id))
+			}
+		}
+	}
+	if !ms.isDestructive() {
+		return none[string]()
+	}
+	switch ms.base.mustGet().(type) {
+	case *table, *column:
+		if ms.kind == migrationKindDrop {
+			return some(fmt.Sprintf("%s is dropped and its data is lost", ms.id))
+		}
+		return some(fmt.Sprintf("%s is recreated and its data is lost", ms.id))
+	case *sequence:
+		if ms.kind == migrationKindDrop {
+			return some(fmt.Sprintf("%s is dropped and its counter is lost", ms.id))
+		}
+		return some(fmt.Sprintf("%s is recreated and its counter is reset", ms.id))
+	default:
+		return none[string]()
+	}
+}
+
 func (ms migrationState) definition() definition {
 	return ms.target.or(ms.base).mustGet()
 }
@@ -150,14 +648,18 @@ type migration struct {
 	targetDefs *definitions
 	states     map[identifier]migrationState
 	dependOn   map[identifier][]definition
+	errs       []error
+	enumTypes  []string
 }
 
-func newMigration(base, target *definitions) *migration {
+func newMigration(base, target *definitions, enumTypes []string) *migration {
 	m := &migration{
 		base,
 		target,
 		make(map[identifier]migrationState),
 		make(map[identifier][]definition),
+		nil,
+		enumTypes,
 	}
 
 	for id := range base.all {
@@ -185,7 +687,12 @@ func (m *migration) initializeState(id identifier) {
 	}
 
 	m.states[def.id()] = newInitialState(baseOpt, targetOpt)
-	for _, id := range unique(def.dependsOn()) {
+	dependsOn := def.dependsOn()
+	if t, ok := def.(*table); ok {
+		// The foreign keys are recreated when the referenced table or column is recreated.
+		dependsOn = append(dependsOn, t.foreignKeyReferences()...)
+	}
+	for _, id := range unique(dependsOn) {
 		m.dependOn[id] = append(m.dependOn[id], def)
 	}
 }
@@ -208,29 +715,219 @@ func (m *migration) kind(id identifier) migrationKind {
 	return m.states[id].kind
 }
 
-func diffDefinitions(base, target *definitions) ([]ast.DDL, error) {
-	m := newMigration(base, target)
+// fail records an error for the migration that can't be generated.
+func (m *migration) fail(err error) {
+	m.errs = append(m.errs, err)
+}
+
+func diffDefinitions(base, target *definitions, option DiffOption) ([]operation, DiffResult, error) {
+	m := newMigration(base, target, option.EnumTypes)
 
 	// Supported schema update: https://cloud.google.com/spanner/docs/schema-updates?t#supported-updates
 	m.drops(base, target)
 	m.alters(base, target)
 	m.adds(base, target)
+	if len(m.errs) > 0 {
+		return nil, DiffResult{}, errors.Join(m.errs...)
+	}
 
 	var operations []operation
+	var result DiffResult
+	var drops []string
 	for _, state := range m.states {
-		operations = append(operations, state.operations()...)
+		if (option.SafeOnly || option.AdditiveOnly) && state.isDestructive() {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s %s", state.kind, state.id))
+			continue
+		}
+		if state.isDestructive() {
+			drops = append(drops, fmt.Sprintf("%s %s", state.kind, state.id))
+		}
+		if option.WarnDestructive {
+			if warning, ok := state.dataLossWarning().get(); ok {
+				result.Warnings = append(result.Warnings, warning)
+			}
+		}
+		ops := state.operations()
+		if option.AdditiveOnly {
+			ops = slices.DeleteFunc(ops, func(op operation) bool {
+				if !isRemoval(op.ddl) {
+					return false
+				}
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s %s: %s", state.kind, state.id, op.ddl.SQL()))
+				return true
+			})
+		}
+		if option.SafeTypeChange {
+			if safeOps, ok := state.safeTypeChangeOperations(); ok {
+				if refs := m.columnReferences(state); len(refs) > 0 {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("%s is recreated without a temporary column because it is referenced by %s", state.id, strings.Join(refs, ", ")))
+				} else {
+					ops = safeOps
+				}
+			}
+		}
+		if option.IdempotentGrants {
+			ops = revokeBeforeGrant(ops)
+		}
+		if option.AnnotateDrops {
+			m.annotateDrop(state, ops)
+		}
+		if option.Hints {
+			state.addRecoveryHint(ops)
+		}
+		operations = append(operations, ops...)
+	}
+	slices.Sort(result.Skipped)
+	slices.Sort(result.Warnings)
+	if option.MaxDrops != nil && len(drops) > *option.MaxDrops {
+		slices.Sort(drops)
+		return nil, DiffResult{}, &TooManyDropsError{*option.MaxDrops, drops}
 	}
 
-	operations, err := sortOperations(operations)
-	if err != nil {
-		return nil, err
+	return operations, result, nil
+}
+
+// safeTypeChangeOperations returns operations adding a temporary column with the new type and dropping the old column
+// if the state recreates a column to change its type.
+func (ms migrationState) safeTypeChangeOperations() ([]operation, bool) {
+	if ms.kind != migrationKindDropAndAdd || !ms.base.valid || !ms.target.valid {
+		return nil, false
+	}
+	base, ok := ms.base.mustGet().(*column)
+	if !ok {
+		return nil, false
 	}
+	target := ms.target.mustGet().(*column)
+	if equalNode(base.node.Type, target.node.Type) || base.node.DefaultSemantics != nil || target.node.DefaultSemantics != nil {
+		return nil, false
+	}
+
+	tmp := *target.node
+	tmp.Name = &ast.Ident{Name: target.node.Name.Name + "_new"}
+	tmp.NotNull = false // NOT NULL can't be satisfied until the column is backfilled.
+	add := newOperation(target, operationKindAlter, &ast.AlterTable{Name: target.table.node.Name, TableAlteration: &ast.AddColumn{Column: &tmp}})
+	drop := newOperation(base, operationKindAlter, &ast.AlterTable{Name: base.table.node.Name, TableAlteration: &ast.DropColumn{Name: base.node.Name}})
+	drop.comment = fmt.Sprintf("-- backfill before dropping: UPDATE %s SET %s = CAST(%s AS %s) WHERE TRUE;\n",
+		target.table.node.Name.SQL(), tmp.Name.SQL(), base.node.Name.SQL(), target.node.Type.SQL())
+	return []operation{add, drop}, true
+}
 
-	ddls := make([]ast.DDL, 0, len(operations))
-	for _, op := range operations {
-		ddls = append(ddls, op.ddl)
+// columnReferences returns the definitions and constraints referencing the column of the state, such as indexes and
+// generated columns. A referenced column can't be replaced by a temporary column, because the references can't be moved.
+func (m *migration) columnReferences(state migrationState) []string {
+	var refs []string
+	for _, def := range m.dependOn[state.id] {
+		refs = append(refs, def.id().String())
+	}
+	for _, def := range []definition{state.base.mustGet(), state.target.mustGet()} {
+		col := def.(*column)
+		for _, tc := range col.table.node.TableConstraints {
+			var cols []*ast.Ident
+			switch c := tc.Constraint.(type) {
+			case *ast.Check:
+				cols = columnsInExpr(c.Expr)
+			case *ast.ForeignKey:
+				cols = c.Columns
+			}
+			if !slices.ContainsFunc(cols, func(ident *ast.Ident) bool { return ident.Name == col.node.Name.Name }) {
+				continue
+			}
+			if tc.Name != nil {
+				refs = append(refs, fmt.Sprintf("%s:Constraint(%s)", col.table.id(), tc.Name.Name))
+			} else {
+				refs = append(refs, fmt.Sprintf("%s:Constraint(%s)", col.table.id(), tc.Constraint.SQL()))
+			}
+		}
+	}
+	slices.Sort(refs)
+	return unique(refs)
+}
+
+// revokeBeforeGrant inserts a REVOKE of the same privilege before each GRANT.
+// Spanner has no GRANT IF NOT GRANTED, and the REVOKE makes the GRANT succeed even if it was already applied.
+func revokeBeforeGrant(ops []operation) []operation {
+	var result []operation
+	for _, op := range ops {
+		if g, ok := op.ddl.(*ast.Grant); ok {
+			revoke := op
+			revoke.ddl = &ast.Revoke{Roles: g.Roles, Privilege: g.Privilege}
+			revoke.comment = ""
+			result = append(result, revoke)
+		}
+		result = append(result, op)
+	}
+	return result
+}
+
+// isRemoval reports whether the DDL removes something from the schema.
+func isRemoval(ddl ast.DDL) bool {
+	switch ddl := ddl.(type) {
+	case *ast.Revoke:
+		return true
+	case *ast.AlterTable:
+		switch alt := ddl.TableAlteration.(type) {
+		case *ast.DropColumn, *ast.DropConstraint, *ast.DropRowDeletionPolicy, *ast.DropSynonym:
+			return true
+		case *ast.AlterColumn:
+			_, ok := alt.Alteration.(*ast.AlterColumnDropDefault)
+			return ok
+		}
+	case *ast.AlterIndex:
+		_, ok := ddl.IndexAlteration.(*ast.DropStoredColumn)
+		return ok
+	case *ast.AlterSearchIndex:
+		_, ok := ddl.IndexAlteration.(*ast.DropStoredColumn)
+		return ok
+	case *ast.AlterChangeStream:
+		_, ok := ddl.ChangeStreamAlteration.(*ast.ChangeStreamDropForAll)
+		return ok
+	}
+	return false
+}
+
+// annotateDrop adds a comment listing dependents dropped together to the drop operation of the state.
+func (m *migration) annotateDrop(state migrationState, ops []operation) {
+	if !state.isDestructive() {
+		return
+	}
+	var dependents []string
+	for _, def := range m.dependOn[state.id] {
+		if dep := m.states[def.id()]; dep.isDestructive() {
+			dependents = append(dependents, dep.id.String())
+		}
+	}
+	if len(dependents) == 0 {
+		return
+	}
+	slices.Sort(dependents)
+	for i := range ops {
+		if ops[i].kind == operationKindDrop {
+			ops[i].comment = fmt.Sprintf("-- dependents dropped together: %s\n", strings.Join(unique(dependents), ", "))
+		}
+	}
+}
+
+// addRecoveryHint adds a comment explaining the data loss to the drop operation of the state.
+func (ms migrationState) addRecoveryHint(ops []operation) {
+	if !ms.isDestructive() {
+		return
+	}
+	var hint string
+	switch base := ms.base.mustGet().(type) {
+	case *table:
+		hint = fmt.Sprintf("-- WARNING: data in %s will be lost. Export it or take a backup before applying.\n", base.node.Name.SQL())
+	case *column:
+		hint = fmt.Sprintf("-- WARNING: data in %s.%s will be lost. Export it or take a backup before applying.\n", base.table.node.Name.SQL(), base.node.Name.SQL())
+	case *sequence:
+		hint = fmt.Sprintf("-- WARNING: the counter of %s will be lost. Check GET_INTERNAL_SEQUENCE_STATE before applying and set start_counter_with to continue it.\n", base.node.Name.SQL())
+	default:
+		return
+	}
+	for i := range ops {
+		if ops[i].kind == operationKindDrop {
+			ops[i].comment = hint + ops[i].comment
+		}
 	}
-	return ddls, nil
 }
 
 func (m *migration) drops(baseDefs, targetDefs *definitions) {
@@ -255,7 +952,7 @@ func (m *migration) alters(base, target *definitions) {
 		if !ok {
 			continue
 		}
-		if equalNode(b.astNode(), t.astNode()) {
+		if equalDefinition(b, t) {
 			continue
 		}
 		b.alter(t, m)
@@ -2,10 +2,15 @@ package spannerdiff
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/cloudspannerecosystem/memefish"
+	"github.com/cloudspannerecosystem/memefish/ast"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -39,6 +44,40 @@ func TestDiff(t *testing.T) {
 			DROP SCHEMA S1;`,
 			false,
 		},
+		"add schema with objects": {
+			``,
+			`
+			CREATE SCHEMA S1;
+			CREATE TABLE S1.T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY (T1_I1);
+			CREATE INDEX S1.IDX1 ON S1.T1 (T1_I1);
+			CREATE SEQUENCE S1.SEQ1 OPTIONS (sequence_kind = 'bit_reversed_positive');`,
+			`
+			CREATE SCHEMA S1;
+			CREATE TABLE S1.T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE INDEX S1.IDX1 ON S1.T1(T1_I1);
+			CREATE SEQUENCE S1.SEQ1 OPTIONS (sequence_kind = 'bit_reversed_positive');`,
+			false,
+		},
+		"drop schema with objects": {
+			`
+			CREATE SCHEMA S1;
+			CREATE TABLE S1.T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY (T1_I1);
+			CREATE INDEX S1.IDX1 ON S1.T1 (T1_I1);
+			CREATE SEQUENCE S1.SEQ1 OPTIONS (sequence_kind = 'bit_reversed_positive');
+			CREATE VIEW S1.V1 SQL SECURITY INVOKER AS SELECT 1 AS X;`,
+			``,
+			`
+			DROP VIEW S1.V1;
+			DROP SEQUENCE S1.SEQ1;
+			DROP INDEX S1.IDX1;
+			DROP TABLE S1.T1;
+			DROP SCHEMA S1;`,
+			false,
+		},
 		"add table": {
 			``,
 			`
@@ -77,6 +116,121 @@ func TestDiff(t *testing.T) {
 			) PRIMARY KEY(T1_I1, T1_S1);`,
 			false,
 		},
+		"recreate interleaved table with parent": {
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1), INTERLEAVE IN PARENT T1 ON DELETE CASCADE;`,
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1 DESC);
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1), INTERLEAVE IN PARENT T1 ON DELETE CASCADE;`,
+			`
+			DROP TABLE T2;
+			DROP TABLE T1;
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1 DESC);
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1), INTERLEAVE IN PARENT T1 ON DELETE CASCADE;`,
+			false,
+		},
+		"recreate interleaved table as standalone": {
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1), INTERLEAVE IN PARENT T1 ON DELETE CASCADE;`,
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1);`,
+			`
+			DROP TABLE T2;
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1);`,
+			false,
+		},
+		"recreate standalone table as interleaved": {
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1);`,
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1), INTERLEAVE IN PARENT T1 ON DELETE CASCADE;`,
+			`
+			DROP TABLE T2;
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1), INTERLEAVE IN PARENT T1 ON DELETE CASCADE;`,
+			false,
+		},
+		"drop parent of table made standalone": {
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1), INTERLEAVE IN PARENT T1 ON DELETE CASCADE;`,
+			`
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1);`,
+			`
+			DROP TABLE T2;
+			DROP TABLE T1;
+			CREATE TABLE T2 (T1_I1 INT64 NOT NULL, T2_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1, T2_I1);`,
+			false,
+		},
+		"create all": {
+			``,
+			`
+			CREATE TABLE Singers (SingerId INT64 NOT NULL, Name STRING(MAX)) PRIMARY KEY (SingerId);
+			CREATE TABLE Albums (SingerId INT64 NOT NULL, AlbumId INT64 NOT NULL, Title STRING(MAX)) PRIMARY KEY (SingerId, AlbumId), INTERLEAVE IN PARENT Singers ON DELETE CASCADE;
+			CREATE TABLE Songs (SingerId INT64 NOT NULL, AlbumId INT64 NOT NULL, SongId INT64 NOT NULL, Title STRING(MAX)) PRIMARY KEY (SingerId, AlbumId, SongId), INTERLEAVE IN PARENT Albums ON DELETE CASCADE;
+			CREATE INDEX AlbumsByTitle ON Albums (Title);
+			CREATE INDEX SongsByTitle ON Songs (SingerId, Title), INTERLEAVE IN Singers;
+			CREATE VIEW AlbumTitles SQL SECURITY INVOKER AS SELECT a.Title FROM Albums AS a;
+			CREATE ROLE Reader;
+			GRANT SELECT ON TABLE Albums, Songs TO ROLE Reader;
+			GRANT SELECT ON VIEW AlbumTitles TO ROLE Reader;`,
+			`
+			CREATE ROLE Reader;
+			CREATE TABLE Singers (SingerId INT64 NOT NULL, Name STRING(MAX)) PRIMARY KEY (SingerId);
+			CREATE TABLE Albums (SingerId INT64 NOT NULL, AlbumId INT64 NOT NULL, Title STRING(MAX)) PRIMARY KEY (SingerId, AlbumId), INTERLEAVE IN PARENT Singers ON DELETE CASCADE;
+			GRANT SELECT ON TABLE Albums TO ROLE Reader;
+			CREATE TABLE Songs (SingerId INT64 NOT NULL, AlbumId INT64 NOT NULL, SongId INT64 NOT NULL, Title STRING(MAX)) PRIMARY KEY (SingerId, AlbumId, SongId), INTERLEAVE IN PARENT Albums ON DELETE CASCADE;
+			GRANT SELECT ON TABLE Songs TO ROLE Reader;
+			CREATE VIEW AlbumTitles SQL SECURITY INVOKER AS SELECT a.Title FROM Albums AS a;
+			GRANT SELECT ON VIEW AlbumTitles TO ROLE Reader;
+			CREATE INDEX AlbumsByTitle ON Albums(Title);
+			CREATE INDEX SongsByTitle ON Songs(SingerId, Title), INTERLEAVE IN Singers;`,
+			false,
+		},
+		"primary key explicit asc": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1 ASC)`,
+			``,
+			false,
+		},
+		"recreate table on primary key direction": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1 DESC)`,
+			`
+			DROP TABLE T1;
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1 DESC);`,
+			false,
+		},
+		"table if not exists": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			CREATE TABLE IF NOT EXISTS T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
+			``,
+			false,
+		},
 		"add foreign key": {
 			`
 			CREATE TABLE T1 (
@@ -128,6 +282,151 @@ func TestDiff(t *testing.T) {
 			ALTER TABLE T1 ADD CONSTRAINT FK1 FOREIGN KEY (T1_S1) REFERENCES T2(T2_S1);`,
 			false,
 		},
+		"add foreign key to table in other schema": {
+			``,
+			`
+			CREATE SCHEMA S1;
+			CREATE SCHEMA S2;
+			CREATE TABLE S1.T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			  CONSTRAINT FK1 FOREIGN KEY (T1_I2) REFERENCES S2.T2 (T2_I1),
+			) PRIMARY KEY (T1_I1);
+			CREATE TABLE S2.T2 (
+			  T2_I1 INT64 NOT NULL,
+			) PRIMARY KEY (T2_I1);`,
+			`
+			CREATE SCHEMA S1;
+			CREATE SCHEMA S2;
+			CREATE TABLE S2.T2 (T2_I1 INT64 NOT NULL) PRIMARY KEY (T2_I1);
+			CREATE TABLE S1.T1 (T1_I1 INT64 NOT NULL, T1_I2 INT64, CONSTRAINT FK1 FOREIGN KEY (T1_I2) REFERENCES S2.T2 (T2_I1)) PRIMARY KEY (T1_I1);`,
+			false,
+		},
+		"recreate foreign key by recreate referenced table": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			  CONSTRAINT FK1 FOREIGN KEY (T1_I2) REFERENCES T2 (T2_I1),
+			) PRIMARY KEY(T1_I1);
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_I2 INT64 NOT NULL,
+			) PRIMARY KEY(T2_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			  CONSTRAINT FK1 FOREIGN KEY (T1_I2) REFERENCES T2 (T2_I1),
+			) PRIMARY KEY(T1_I1);
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_I2 INT64 NOT NULL,
+			) PRIMARY KEY(T2_I1, T2_I2);`,
+			`
+			ALTER TABLE T1 DROP CONSTRAINT FK1;
+			DROP TABLE T2;
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_I2 INT64 NOT NULL,
+			) PRIMARY KEY(T2_I1, T2_I2);
+			ALTER TABLE T1 ADD CONSTRAINT FK1 FOREIGN KEY (T1_I2) REFERENCES T2(T2_I1);`,
+			false,
+		},
+		"recreate multi-column foreign key by recreate referenced column": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			  T1_S1 STRING(MAX),
+			  CONSTRAINT FK1 FOREIGN KEY (T1_I2, T1_S1) REFERENCES T2 (T2_I1, T2_S1),
+			) PRIMARY KEY(T1_I1);
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_S1 INT64 NOT NULL,
+			) PRIMARY KEY(T2_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			  T1_S1 STRING(MAX),
+			  T1_S2 STRING(MAX),
+			  CONSTRAINT FK1 FOREIGN KEY (T1_I2, T1_S1) REFERENCES T2 (T2_I1, T2_S1),
+			) PRIMARY KEY(T1_I1);
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_S1 STRING(MAX) NOT NULL,
+			) PRIMARY KEY(T2_I1);`,
+			`
+			ALTER TABLE T1 DROP CONSTRAINT FK1;
+			ALTER TABLE T2 DROP COLUMN T2_S1;
+			ALTER TABLE T2 ADD COLUMN T2_S1 STRING(MAX) NOT NULL;
+			ALTER TABLE T1 ADD CONSTRAINT FK1 FOREIGN KEY (T1_I2, T1_S1) REFERENCES T2(T2_I1, T2_S1);
+			ALTER TABLE T1 ADD COLUMN T1_S2 STRING(MAX);`,
+			false,
+		},
+		"recreate multi-column foreign key once by recreate referenced columns": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64,
+			  T1_I2 INT64,
+			  T1_I3 INT64,
+			) PRIMARY KEY(T1_I3);
+			CREATE TABLE T2 (
+			  T2_I1 INT64,
+			  T2_I2 INT64,
+			  CONSTRAINT FK1 FOREIGN KEY (T2_I1, T2_I2) REFERENCES T1 (T1_I1, T1_I2),
+			) PRIMARY KEY(T2_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 STRING(MAX),
+			  T1_I2 STRING(MAX),
+			  T1_I3 INT64,
+			) PRIMARY KEY(T1_I3);
+			CREATE TABLE T2 (
+			  T2_I1 INT64,
+			  T2_I2 INT64,
+			  CONSTRAINT FK1 FOREIGN KEY (T2_I1, T2_I2) REFERENCES T1 (T1_I1, T1_I2),
+			) PRIMARY KEY(T2_I1);`,
+			`
+			ALTER TABLE T2 DROP CONSTRAINT FK1;
+			ALTER TABLE T1 DROP COLUMN T1_I2;
+			ALTER TABLE T1 DROP COLUMN T1_I1;
+			ALTER TABLE T1 ADD COLUMN T1_I1 STRING(MAX);
+			ALTER TABLE T1 ADD COLUMN T1_I2 STRING(MAX);
+			ALTER TABLE T2 ADD CONSTRAINT FK1 FOREIGN KEY (T2_I1, T2_I2) REFERENCES T1 (T1_I1, T1_I2);`,
+			false,
+		},
+		"add check constraint to tables referencing each other": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			  CONSTRAINT FK1 FOREIGN KEY (T1_I2) REFERENCES T2 (T2_I1),
+			) PRIMARY KEY(T1_I1);
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_I2 INT64,
+			  CONSTRAINT FK2 FOREIGN KEY (T2_I2) REFERENCES T1 (T1_I1),
+			) PRIMARY KEY(T2_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			  CONSTRAINT FK1 FOREIGN KEY (T1_I2) REFERENCES T2 (T2_I1),
+			  CONSTRAINT CK1 CHECK (T1_I2 > 0),
+			) PRIMARY KEY(T1_I1);
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_I2 INT64,
+			  CONSTRAINT FK2 FOREIGN KEY (T2_I2) REFERENCES T1 (T1_I1),
+			  CONSTRAINT CK2 CHECK (T2_I2 > 0),
+			) PRIMARY KEY(T2_I1);`,
+			`
+			ALTER TABLE T1 ADD CONSTRAINT CK1 CHECK (T1_I2 > 0);
+			ALTER TABLE T2 ADD CONSTRAINT CK2 CHECK (T2_I2 > 0);`,
+			false,
+		},
 		"add check constraint": {
 			`
 			CREATE TABLE T1 (
@@ -172,6 +471,50 @@ func TestDiff(t *testing.T) {
 			ALTER TABLE T1 ADD CONSTRAINT CHK1 CHECK (T1_I1 > 1);`,
 			false,
 		},
+		"reformatted check constraint": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  CONSTRAINT CK1 CHECK (T1_I1>0),
+			) PRIMARY KEY(T1_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  CONSTRAINT CK1 CHECK (
+			    T1_I1 > 0
+			  ),
+			) PRIMARY KEY(T1_I1);`,
+			``,
+			false,
+		},
+		"add unnamed check constraint": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  CHECK (T1_I1 > 0)
+			) PRIMARY KEY(T1_I1)`,
+			`
+			ALTER TABLE T1 ADD CHECK (T1_I1 > 0);`,
+			false,
+		},
+		"drop unnamed check constraint": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  CHECK (T1_I1 > 0)
+			) PRIMARY KEY(T1_I1)`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  CHECK (T1_I1 > 1)
+			) PRIMARY KEY(T1_I1)`,
+			``,
+			true,
+		},
 		"add row deletion policy": {
 			`
 			CREATE TABLE T1 (
@@ -187,6 +530,21 @@ func TestDiff(t *testing.T) {
 			ALTER TABLE T1 ADD ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 1 DAY));`,
 			false,
 		},
+		"add row deletion policy with new column": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_TS1 TIMESTAMP NOT NULL,
+			) PRIMARY KEY(T1_I1), ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 1 DAY));`,
+			`
+			ALTER TABLE T1 ADD COLUMN T1_TS1 TIMESTAMP NOT NULL;
+			ALTER TABLE T1 ADD ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 1 DAY));`,
+			false,
+		},
 		"drop row deletion policy": {
 			`
 			CREATE TABLE T1 (
@@ -217,6 +575,20 @@ func TestDiff(t *testing.T) {
 			ALTER TABLE T1 REPLACE ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 2 DAY));`,
 			false,
 		},
+		"equivalent row deletion policy interval": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_TS1 TIMESTAMP NOT NULL,
+			) PRIMARY KEY(T1_I1), ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 30 DAY));`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_TS1 TIMESTAMP NOT NULL,
+			) PRIMARY KEY(T1_I1), ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 0x1E DAY));`,
+			``,
+			false,
+		},
 		"add synonym": {
 			`
 			CREATE TABLE T1 (
@@ -245,6 +617,39 @@ func TestDiff(t *testing.T) {
 			ALTER TABLE T1 DROP SYNONYM T2;`,
 			false,
 		},
+		"multiple table alterations": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_TS1 TIMESTAMP NOT NULL,
+			  CONSTRAINT CK_C CHECK (T1_I1 > 3),
+			  CONSTRAINT CK_A CHECK (T1_I1 > 1),
+			  SYNONYM(S_B),
+			  SYNONYM(S_A),
+			) PRIMARY KEY (T1_I1), ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 1 DAY))`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_TS1 TIMESTAMP NOT NULL,
+			  CONSTRAINT CK_D CHECK (T1_I1 > 4),
+			  CONSTRAINT CK_B CHECK (T1_I1 > 2),
+			  CONSTRAINT CK_A CHECK (T1_I1 > 0),
+			  SYNONYM(S_D),
+			  SYNONYM(S_C),
+			) PRIMARY KEY (T1_I1), ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 2 DAY))`,
+			`
+			ALTER TABLE T1 REPLACE ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 2 DAY));
+			ALTER TABLE T1 DROP SYNONYM S_A;
+			ALTER TABLE T1 DROP SYNONYM S_B;
+			ALTER TABLE T1 ADD SYNONYM S_C;
+			ALTER TABLE T1 ADD SYNONYM S_D;
+			ALTER TABLE T1 DROP CONSTRAINT CK_A;
+			ALTER TABLE T1 DROP CONSTRAINT CK_C;
+			ALTER TABLE T1 ADD CONSTRAINT CK_A CHECK (T1_I1 > 0);
+			ALTER TABLE T1 ADD CONSTRAINT CK_B CHECK (T1_I1 > 2);
+			ALTER TABLE T1 ADD CONSTRAINT CK_D CHECK (T1_I1 > 4);`,
+			false,
+		},
 		"recreate synonym": {
 			`
 			CREATE TABLE T1 (
@@ -334,524 +739,2899 @@ func TestDiff(t *testing.T) {
 			ALTER TABLE T1 DROP COLUMN T1_S1;`,
 			false,
 		},
-		"alter column": {
+		"escaped option description": {
 			`
 			CREATE TABLE T1 (
 			  T1_I1 INT64 NOT NULL,
-			  T1_S1 STRING(MAX),
+			  T1_S1 STRING(MAX) OPTIONS (description = 'it\'s\na é'),
 			) PRIMARY KEY(T1_I1)`,
 			`
 			CREATE TABLE T1 (
 			  T1_I1 INT64 NOT NULL,
-			  T1_S1 STRING(100),
+			  T1_S1 STRING(MAX) OPTIONS (description = """it's
+a é"""),
 			) PRIMARY KEY(T1_I1)`,
-			`
-			ALTER TABLE T1 ALTER COLUMN T1_S1 STRING(100);`,
+			``,
 			false,
 		},
-		"recreate column": {
+		"add column with escaped option description": {
 			`
 			CREATE TABLE T1 (
 			  T1_I1 INT64 NOT NULL,
-			  T1_S1 STRING(MAX),
 			) PRIMARY KEY(T1_I1)`,
 			`
 			CREATE TABLE T1 (
 			  T1_I1 INT64 NOT NULL,
-			  T1_S1 INT64,
+			  T1_S1 STRING(MAX) OPTIONS (description = """it's "quoted"
+a é"""),
 			) PRIMARY KEY(T1_I1)`,
 			`
-			ALTER TABLE T1 DROP COLUMN T1_S1;
-			ALTER TABLE T1 ADD COLUMN T1_S1 INT64;`,
+			ALTER TABLE T1 ADD COLUMN T1_S1 STRING(MAX) OPTIONS (description = 'it\'s "quoted"\na é');`,
 			false,
 		},
-		"add index": {
-			``,
+		"add column option keeping others": {
 			`
-			CREATE INDEX IDX1 ON T1(T1_S1)`,
-			`
-			CREATE INDEX IDX1 ON T1(T1_S1);`,
-			false,
-		},
-		"drop index": {
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_T1 TIMESTAMP OPTIONS (allow_commit_timestamp = true),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE INDEX IDX1 ON T1(T1_S1)`,
-			``,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_T1 TIMESTAMP OPTIONS (allow_commit_timestamp = true, locality_group = 'LG1'),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			DROP INDEX IDX1;`,
+			ALTER TABLE T1 ALTER COLUMN T1_T1 SET OPTIONS (allow_commit_timestamp = true, locality_group = 'LG1');`,
 			false,
 		},
-		"recreate index": {
+		"remove column option": {
 			`
-			CREATE INDEX IDX1 ON T1(T1_I1)`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_T1 TIMESTAMP OPTIONS (allow_commit_timestamp = true, locality_group = 'LG1'),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE INDEX IDX1 ON T1(T1_I1, T1_S1)`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_T1 TIMESTAMP OPTIONS (locality_group = 'LG1'),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			DROP INDEX IDX1;
-			CREATE INDEX IDX1 ON T1(T1_I1, T1_S1);`,
+			ALTER TABLE T1 ALTER COLUMN T1_T1 SET OPTIONS (locality_group = 'LG1', allow_commit_timestamp = NULL);`,
 			false,
 		},
-		"add index storing": {
+		"remove all column options": {
 			`
-			CREATE INDEX IDX1 ON T1(T1_S1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_T1 TIMESTAMP OPTIONS (allow_commit_timestamp = true),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE INDEX IDX1 ON T1(T1_S1) STORING (T1_I1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_T1 TIMESTAMP,
+			) PRIMARY KEY(T1_I1)`,
 			`
-			ALTER INDEX IDX1 ADD STORED COLUMN T1_I1;`,
+			ALTER TABLE T1 ALTER COLUMN T1_T1 SET OPTIONS (allow_commit_timestamp = NULL);`,
 			false,
 		},
-		"drop index storing": {
+
+		"alter column": {
 			`
-			CREATE INDEX IDX1 ON T1(T1_S1) STORING (T1_I1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE INDEX IDX1 ON T1(T1_S1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(100),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			ALTER INDEX IDX1 DROP STORED COLUMN T1_I1;`,
+			ALTER TABLE T1 ALTER COLUMN T1_S1 STRING(100);`,
 			false,
 		},
-		"add search index": {
-			``,
-			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_S1)`,
+		"alter column type and drop default": {
 			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_S1);`,
-			false,
-		},
-		"drop search index": {
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX) DEFAULT ("a"),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_S1)`,
-			``,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(100),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			DROP SEARCH INDEX IDX1;`,
+			ALTER TABLE T1 ALTER COLUMN T1_S1 STRING(100);
+			ALTER TABLE T1 ALTER COLUMN T1_S1 DROP DEFAULT;`,
 			false,
 		},
-		"recreate search index": {
+		"alter column type referenced by check constraint": {
 			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_I1)`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			  CONSTRAINT CK1 CHECK (LENGTH(T1_S1) > 0),
+			  CONSTRAINT CK2 CHECK (T1_I1 > 0),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_I1, T1_S1)`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(100),
+			  CONSTRAINT CK1 CHECK (LENGTH(T1_S1) > 0),
+			  CONSTRAINT CK2 CHECK (T1_I1 > 0),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			DROP SEARCH INDEX IDX1;
-			CREATE SEARCH INDEX IDX1 ON T1(T1_I1, T1_S1);`,
+			ALTER TABLE T1 DROP CONSTRAINT CK1;
+			ALTER TABLE T1 ALTER COLUMN T1_S1 STRING(100);
+			ALTER TABLE T1 ADD CONSTRAINT CK1 CHECK (LENGTH(T1_S1) > 0);`,
 			false,
 		},
-		"add search index storing": {
+		"alter default of indexed column": {
 			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_S1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX) DEFAULT ("a"),
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
 			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) STORING (T1_I1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX) DEFAULT ("b"),
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
 			`
-			ALTER SEARCH INDEX IDX1 ADD STORED COLUMN T1_I1;`,
+			ALTER TABLE T1 ALTER COLUMN T1_S1 SET DEFAULT ("b");`,
 			false,
 		},
-		"drop search index storing": {
+		"alter proto column message type": {
 			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) STORING (T1_I1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_P1 ` + "`test.Foo`" + `,
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE SEARCH INDEX IDX1 ON T1(T1_S1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_P1 ` + "`test.Bar`" + `,
+			) PRIMARY KEY(T1_I1)`,
 			`
-			ALTER SEARCH INDEX IDX1 DROP STORED COLUMN T1_I1;`,
+			ALTER TABLE T1 ALTER COLUMN T1_P1 ` + "`test.Bar`" + `;`,
 			false,
 		},
-		"add vector index": {
-			``,
+		"alter int64 column to named type": {
 			`
-			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE');`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_E1 INT64,
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE');`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_E1 ` + "`test.Enum`" + `,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			ALTER TABLE T1 DROP COLUMN T1_E1;
+			ALTER TABLE T1 ADD COLUMN T1_E1 ` + "`test.Enum`" + `;`,
 			false,
 		},
-		"drop vector index": {
+		"add not null to array column": {
 			`
-			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE');`,
-			``,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_AI1 ARRAY<INT64>,
+			) PRIMARY KEY(T1_I1)`,
 			`
-			DROP VECTOR INDEX IDX1;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_AI1 ARRAY<INT64> NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			ALTER TABLE T1 DROP COLUMN T1_AI1;
+			ALTER TABLE T1 ADD COLUMN T1_AI1 ARRAY<INT64> NOT NULL;`,
 			false,
 		},
-		"recreate vector index": {
+		"remove not null from array column": {
 			`
-			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE');`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_AI1 ARRAY<INT64> NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'EUCLIDEAN');`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_AI1 ARRAY<INT64>,
+			) PRIMARY KEY(T1_I1)`,
 			`
-			DROP VECTOR INDEX IDX1;
-			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'EUCLIDEAN');`,
+			ALTER TABLE T1 ALTER COLUMN T1_AI1 ARRAY<INT64>;`,
 			false,
 		},
-		"add property graph": {
-			``,
+		"recreate column": {
 			`
-			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);
-			`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1)`,
 			`
-			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 INT64,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			ALTER TABLE T1 DROP COLUMN T1_S1;
+			ALTER TABLE T1 ADD COLUMN T1_S1 INT64;`,
 			false,
 		},
-		"drop property graph": {
+		"reorder columns": {
 			`
-			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);`,
-			``,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			  T1_I2 INT64,
+			) PRIMARY KEY(T1_I1)`,
 			`
-			DROP PROPERTY GRAPH G1;`,
+			CREATE TABLE T1 (
+			  T1_I2 INT64,
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1)`,
+			``,
 			false,
 		},
-		"recreate property graph": {
+		"recreate tokenlist column": {
 			`
-			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			  T1_T1 TOKENLIST AS (TOKENIZE_FULLTEXT(T1_S1)) HIDDEN,
+			) PRIMARY KEY(T1_I1);
+			CREATE SEARCH INDEX IDX1 ON T1(T1_T1);`,
 			`
-			CREATE PROPERTY GRAPH G1 NODE TABLES (T1);`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			  T1_T1 TOKENLIST AS (TOKENIZE_SUBSTRING(T1_S1)) HIDDEN,
+			) PRIMARY KEY(T1_I1);
+			CREATE SEARCH INDEX IDX1 ON T1(T1_T1);`,
 			`
-			CREATE OR REPLACE PROPERTY GRAPH G1 NODE TABLES (T1);`,
+			DROP SEARCH INDEX IDX1;
+			ALTER TABLE T1 DROP COLUMN T1_T1;
+			ALTER TABLE T1 ADD COLUMN T1_T1 TOKENLIST AS (TOKENIZE_SUBSTRING(T1_S1)) HIDDEN;
+			CREATE SEARCH INDEX IDX1 ON T1(T1_T1);`,
 			false,
 		},
-		"create view": {
-			``,
+		"recreate stored generated column as non-stored": {
 			`
-			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 AS (T1_I1 * 2) STORED,
+			) PRIMARY KEY(T1_I1);`,
 			`
-			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 AS (T1_I1 * 2),
+			) PRIMARY KEY(T1_I1);`,
+			`
+			ALTER TABLE T1 DROP COLUMN T1_I2;
+			ALTER TABLE T1 ADD COLUMN T1_I2 INT64 AS (T1_I1 * 2);`,
 			false,
 		},
-		"drop view": {
+		"recreate default column as generated column": {
 			`
-			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
-			``,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 DEFAULT (2),
+			) PRIMARY KEY(T1_I1);`,
 			`
-			DROP VIEW V1;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 AS (T1_I1 * 2) STORED,
+			) PRIMARY KEY(T1_I1);`,
+			`
+			ALTER TABLE T1 DROP COLUMN T1_I2;
+			ALTER TABLE T1 ADD COLUMN T1_I2 INT64 AS (T1_I1 * 2) STORED;`,
 			false,
 		},
-		"recreate view": {
+		"recreate generated column as default column": {
 			`
-			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 NOT NULL AS (T1_I1 * 2) STORED,
+			) PRIMARY KEY(T1_I1);`,
 			`
-			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1 WHERE T1_I1 > 0;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 NOT NULL DEFAULT (2),
+			) PRIMARY KEY(T1_I1);`,
 			`
-			CREATE OR REPLACE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1 WHERE T1_I1 > 0;`,
+			ALTER TABLE T1 DROP COLUMN T1_I2;
+			ALTER TABLE T1 ADD COLUMN T1_I2 INT64 NOT NULL DEFAULT (2);`,
 			false,
 		},
-		"drop and create view": {
+		"recreate generated column as plain column": {
 			`
 			CREATE TABLE T1 (
 			  T1_I1 INT64 NOT NULL,
-			) PRIMARY KEY(T1_I1);
-			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			  T1_I2 INT64 AS (T1_I1 * 2) STORED,
+			) PRIMARY KEY(T1_I1);`,
 			`
 			CREATE TABLE T1 (
-			  T1_S1 STRING(MAX) NOT NULL,
-			) PRIMARY KEY(T1_S1);
-			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			) PRIMARY KEY(T1_I1);`,
 			`
-			DROP VIEW V1;
-			DROP TABLE T1;
-			CREATE TABLE T1 (
-			  T1_S1 STRING(MAX) NOT NULL,
-			) PRIMARY KEY(T1_S1);
-			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			ALTER TABLE T1 DROP COLUMN T1_I2;
+			ALTER TABLE T1 ADD COLUMN T1_I2 INT64;`,
 			false,
 		},
-		"add change stream": {
-			``,
+		"drop generated column and referenced column": {
 			`
-			CREATE CHANGE STREAM S1 FOR ALL;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_Z1 INT64,
+			  T1_A1 INT64 AS (T1_Z1 * 2) STORED,
+			) PRIMARY KEY(T1_I1);`,
 			`
-			CREATE CHANGE STREAM S1 FOR ALL;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1);`,
+			`
+			ALTER TABLE T1 DROP COLUMN T1_A1;
+			ALTER TABLE T1 DROP COLUMN T1_Z1;`,
 			false,
 		},
-		"drop change stream": {
+		"add generated column and referenced column": {
 			`
-			CREATE CHANGE STREAM S1 FOR ALL;`,
-			``,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1);`,
 			`
-			DROP CHANGE STREAM S1;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_Z1 INT64,
+			  T1_A1 INT64 AS (T1_Z1 * 2) STORED,
+			) PRIMARY KEY(T1_I1);`,
+			`
+			ALTER TABLE T1 ADD COLUMN T1_Z1 INT64;
+			ALTER TABLE T1 ADD COLUMN T1_A1 INT64 AS (T1_Z1 * 2) STORED;`,
 			false,
 		},
-		"alter change stream": {
+		"recreate generated column with referenced column": {
 			`
-			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '36h' );`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_Z1 INT64,
+			  T1_A1 INT64 AS (T1_Z1 * 2) STORED,
+			) PRIMARY KEY(T1_I1);`,
 			`
-			CREATE CHANGE STREAM S1 FOR T1(T1_I1) OPTIONS ( retention_period = '72h' );`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_Z1 STRING(MAX),
+			  T1_A1 INT64 AS (LENGTH(T1_Z1)) STORED,
+			) PRIMARY KEY(T1_I1);`,
 			`
-			ALTER CHANGE STREAM S1 SET FOR T1(T1_I1);
-			ALTER CHANGE STREAM S1 SET OPTIONS ( retention_period = '72h' );`,
+			ALTER TABLE T1 DROP COLUMN T1_A1;
+			ALTER TABLE T1 DROP COLUMN T1_Z1;
+			ALTER TABLE T1 ADD COLUMN T1_Z1 STRING(MAX);
+			ALTER TABLE T1 ADD COLUMN T1_A1 INT64 AS (LENGTH(T1_Z1)) STORED;`,
 			false,
 		},
-		"add sequence": {
+		"add index": {
 			``,
 			`
-			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');`,
+			CREATE INDEX IDX1 ON T1(T1_S1)`,
 			`
-			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');`,
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
 			false,
 		},
-		"drop sequence": {
+		"drop index": {
 			`
-			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');`,
+			CREATE INDEX IDX1 ON T1(T1_S1)`,
 			``,
 			`
-			DROP SEQUENCE S1;`,
+			DROP INDEX IDX1;`,
 			false,
 		},
-		"alter sequence": {
+		"recreate index": {
 			`
-			CREATE SEQUENCE S1 OPTIONS (skip_range_min = 1000, skip_range_max = 2000);`,
+			CREATE INDEX IDX1 ON T1(T1_I1)`,
 			`
-			CREATE SEQUENCE S1 OPTIONS (start_counter_with = 10);`,
+			CREATE INDEX IDX1 ON T1(T1_I1, T1_S1)`,
 			`
-			ALTER SEQUENCE S1 SET OPTIONS (start_counter_with = 10);`,
+			DROP INDEX IDX1;
+			CREATE INDEX IDX1 ON T1(T1_I1, T1_S1);`,
 			false,
 		},
-		"add model": {
+		"add table with index": {
 			``,
 			`
-			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			CREATE INDEX IDX1 ON T1(T1_S1);
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1);`,
 			`
-			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
 			false,
 		},
-		"drop model": {
+		"drop table with index": {
 			`
-			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
 			``,
 			`
-			DROP MODEL M1;`,
+			DROP INDEX IDX1;
+			DROP TABLE T1;`,
 			false,
 		},
-		"alter model": {
-			`
-			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+		"add table with index in schema": {
+			``,
 			`
-			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model2' );`,
+			CREATE INDEX S1.IDX1 ON S1.T1(T1_S1);
+			CREATE TABLE S1.T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1);
+			CREATE SCHEMA S1;`,
 			`
-			ALTER MODEL M1 SET OPTIONS ( endpoint = 'model2' );`,
+			CREATE SCHEMA S1;
+			CREATE TABLE S1.T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX S1.IDX1 ON S1.T1(T1_S1);`,
 			false,
 		},
-		"recreate model": {
+		"move index to another table": {
 			`
-			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			CREATE INDEX IDX1 ON T1(T1_S1)`,
 			`
-			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F3 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			CREATE INDEX IDX1 ON T2(T2_S1)`,
 			`
-			CREATE OR REPLACE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F3 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			DROP INDEX IDX1;
+			CREATE INDEX IDX1 ON T2(T2_S1);`,
 			false,
 		},
-		"add proto bundle": {
-			``,
-			"CREATE PROTO BUNDLE (`test.proto`)",
-			"CREATE PROTO BUNDLE (`test.proto`)",
+		"add index on recreated table": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1)`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I2);
+			CREATE INDEX IDX1 ON T1(T1_I1)`,
+			`
+			DROP TABLE T1;
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I2);
+			CREATE INDEX IDX1 ON T1(T1_I1);`,
 			false,
 		},
-		"drop proto bundle": {
-			"CREATE PROTO BUNDLE (`test.proto`)",
-			``,
-			"DROP PROTO BUNDLE",
+		"add index storing": {
+			`
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
+			`
+			CREATE INDEX IDX1 ON T1(T1_S1) STORING (T1_I1);`,
+			`
+			ALTER INDEX IDX1 ADD STORED COLUMN T1_I1;`,
 			false,
 		},
-		"alter proto bundle": {
-			"CREATE PROTO BUNDLE (`test.proto`)",
-			"CREATE PROTO BUNDLE (`test2.proto`)",
-			"ALTER PROTO BUNDLE INSERT (`test2.proto`) DELETE (`test.proto`)",
+		"drop index storing": {
+			`
+			CREATE INDEX IDX1 ON T1(T1_S1) STORING (T1_I1);`,
+			`
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
+			`
+			ALTER INDEX IDX1 DROP STORED COLUMN T1_I1;`,
 			false,
 		},
-		"proto bundle twice": {
-			"CREATE PROTO BUNDLE (`test.proto`); CREATE PROTO BUNDLE (`test2.proto`)",
-			"CREATE PROTO BUNDLE (`test.proto`); CREATE PROTO BUNDLE (`test2.proto`)",
-			"",
-			true,
-		},
-		"add role": {
-			``,
+		"drop column stored by index": {
 			`
-			CREATE ROLE R1;`,
-			`CREATE ROLE R1;`,
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			  T1_S2 STRING(MAX),
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1) STORING (T1_S2);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
+			`
+			ALTER INDEX IDX1 DROP STORED COLUMN T1_S2;
+			ALTER TABLE T1 DROP COLUMN T1_S2;`,
 			false,
 		},
-		"drop role": {
+		"reorder index storing": {
 			`
-			CREATE ROLE R1;`,
+			CREATE INDEX IDX1 ON T1(T1_S1) STORING (T1_I1, T1_I2);`,
+			`
+			CREATE INDEX IDX1 ON T1(T1_S1) STORING (T1_I2, T1_I1);`,
 			``,
+			false,
+		},
+		"reorder index keys": {
 			`
-			DROP ROLE R1;`,
+			CREATE INDEX IDX1 ON T1(T1_I1, T1_I2);`,
+			`
+			CREATE INDEX IDX1 ON T1(T1_I2, T1_I1);`,
+			`
+			DROP INDEX IDX1;
+			CREATE INDEX IDX1 ON T1(T1_I2, T1_I1);`,
 			false,
 		},
-		"add table grant": {
+		"add search index": {
 			``,
 			`
-			GRANT SELECT, UPDATE ON TABLE T1 TO ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1)`,
 			`
-			GRANT SELECT, UPDATE ON TABLE T1 TO ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1);`,
 			false,
 		},
-		"drop table grant": {
+		"drop search index": {
 			`
-			GRANT SELECT, UPDATE ON TABLE T1 TO ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1)`,
 			``,
 			`
-			REVOKE SELECT, UPDATE ON TABLE T1 FROM ROLE R1;`,
+			DROP SEARCH INDEX IDX1;`,
 			false,
 		},
-		"alter table grant": {
+		"recreate search index": {
 			`
-			GRANT SELECT, SELECT(T1_C1), UPDATE, INSERT(T1_C1, T1_C2) ON TABLE T1 TO ROLE R1;
-			GRANT UPDATE, DELETE ON TABLE T1 TO ROLE R2;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_I1)`,
 			`
-			GRANT SELECT(T1_C2), DELETE ON TABLE T1 TO ROLE R1;
-			GRANT SELECT, UPDATE(T1_C1, T1_C2), UPDATE, INSERT ON TABLE T1 TO ROLE R2;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_I1, T1_S1)`,
 			`
-			REVOKE SELECT, SELECT(T1_C1), UPDATE, INSERT(T1_C1, T1_C2) ON TABLE T1 FROM ROLE R1;
-			GRANT SELECT(T1_C2), DELETE ON TABLE T1 TO ROLE R1;
-			REVOKE DELETE ON TABLE T1 FROM ROLE R2;
-			GRANT SELECT, UPDATE(T1_C1, T1_C2), INSERT ON TABLE T1 TO ROLE R2;`,
+			DROP SEARCH INDEX IDX1;
+			CREATE SEARCH INDEX IDX1 ON T1(T1_I1, T1_S1);`,
 			false,
 		},
-		"add view grant": {
-			``,
+		"add search index storing": {
 			`
-			GRANT SELECT ON VIEW V1 TO ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1);`,
 			`
-			GRANT SELECT ON VIEW V1 TO ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) STORING (T1_I1);`,
+			`
+			ALTER SEARCH INDEX IDX1 ADD STORED COLUMN T1_I1;`,
 			false,
 		},
-		"drop view grant": {
+		"drop search index storing": {
 			`
-			GRANT SELECT ON VIEW V1 TO ROLE R1;`,
-			``,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) STORING (T1_I1);`,
 			`
-			REVOKE SELECT ON VIEW V1 FROM ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1);`,
+			`
+			ALTER SEARCH INDEX IDX1 DROP STORED COLUMN T1_I1;`,
 			false,
 		},
-		"add change stream grant": {
-			``,
+		"reorder search index storing": {
 			`
-			GRANT SELECT ON CHANGE STREAM S1 TO ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) STORING (T1_I1, T1_I2);`,
 			`
-			GRANT SELECT ON CHANGE STREAM S1 TO ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) STORING (T1_I2, T1_I1);`,
+			``,
 			false,
 		},
-		"drop change stream grant": {
+		"search index order by with implicit asc": {
 			`
-			GRANT SELECT ON CHANGE STREAM S1 TO ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) ORDER BY T1_I1;`,
+			`
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) ORDER BY T1_I1 ASC;`,
 			``,
+			false,
+		},
+		"change search index order by direction": {
 			`
-			REVOKE SELECT ON CHANGE STREAM S1 FROM ROLE R1;`,
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) ORDER BY T1_I1;`,
+			`
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) ORDER BY T1_I1 DESC;`,
+			`
+			DROP SEARCH INDEX IDX1;
+			CREATE SEARCH INDEX IDX1 ON T1(T1_S1) ORDER BY T1_I1 DESC;`,
 			false,
 		},
-		"add table function grant": {
+		"add vector index": {
 			``,
 			`
-			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE');`,
 			`
-			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE');`,
 			false,
 		},
-		"drop table function grant": {
+		"drop vector index": {
 			`
-			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE');`,
 			``,
 			`
-			REVOKE EXECUTE ON TABLE FUNCTION READ_CS1 FROM ROLE R1;`,
+			DROP VECTOR INDEX IDX1;`,
 			false,
 		},
-		"add role grant": {
-			``,
+		"recreate vector index": {
 			`
-			GRANT ROLE R2 TO ROLE R1;`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE');`,
 			`
-			GRANT ROLE R2 TO ROLE R1;`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'EUCLIDEAN');`,
+			`
+			DROP VECTOR INDEX IDX1;
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'EUCLIDEAN');`,
 			false,
 		},
-		"drop role grant": {
+		"remove vector index option": {
 			`
-			GRANT ROLE R2 TO ROLE R1;`,
-			``,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE', tree_depth = 3, num_leaves = 1000);`,
 			`
-			REVOKE ROLE R2 FROM ROLE R1;`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE', tree_depth = 3);`,
+			`
+			DROP VECTOR INDEX IDX1;
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE', tree_depth = 3);`,
 			false,
 		},
-		"add alter database": {
-			``,
+		"change vector index option other than distance type": {
 			`
-			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d');`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE', num_leaves = 1000);`,
 			`
-			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d');`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE', num_leaves = 2000);`,
+			`
+			DROP VECTOR INDEX IDX1;
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE', num_leaves = 2000);`,
 			false,
 		},
-		"no drop alter database": {
+		"reorder vector index options": {
 			`
-			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d');`,
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (distance_type = 'COSINE', num_leaves = 1000);`,
+			`
+			CREATE VECTOR INDEX IDX1 ON T1(T1_AF1) OPTIONS (num_leaves = 1000, distance_type = 'COSINE');`,
 			``,
+			false,
+		},
+		"add property graph": {
 			``,
+			`
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);
+			`,
+			`
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);`,
 			false,
 		},
-		"alter alter database": {
+		"drop property graph": {
 			`
-			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d', optimizer_version = 1);`,
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);`,
+			``,
 			`
-			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '2d');`,
+			DROP PROPERTY GRAPH G1;`,
+			false,
+		},
+		"recreate property graph": {
 			`
-			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '2d');`,
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);`,
+			`
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1);`,
+			`
+			CREATE OR REPLACE PROPERTY GRAPH G1 NODE TABLES (T1);`,
 			false,
 		},
-		"issue #35": { // https://github.com/morikuni/spannerdiff/issues/35
+		"alter property graph properties": {
+			`
+			CREATE TABLE T1 (C1 INT64, C2 INT64, C3 INT64) PRIMARY KEY (C1);
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1 KEY (C1) PROPERTIES (C2));`,
+			`
+			CREATE TABLE T1 (C1 INT64, C2 INT64, C3 INT64) PRIMARY KEY (C1);
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1 KEY (C1) LABEL L1 PROPERTIES (C2, C3 + 1 AS P3));`,
+			`
+			CREATE OR REPLACE PROPERTY GRAPH G1 NODE TABLES (T1 KEY (C1) LABEL L1 PROPERTIES (C2, C3 + 1 AS P3));`,
+			false,
+		},
+		"recreate column used in property graph properties": {
+			`
+			CREATE TABLE T1 (C1 INT64, C2 INT64) PRIMARY KEY (C1);
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1 KEY (C1) PROPERTIES (ABS(C2) AS P2));`,
+			`
+			CREATE TABLE T1 (C1 INT64, C2 FLOAT64) PRIMARY KEY (C1);
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1 KEY (C1) PROPERTIES (ABS(C2) AS P2));`,
+			`
+			DROP PROPERTY GRAPH G1;
+			ALTER TABLE T1 DROP COLUMN C2;
+			ALTER TABLE T1 ADD COLUMN C2 FLOAT64;
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1 KEY (C1) PROPERTIES (ABS(C2) AS P2));`,
+			false,
+		},
+		"create view": {
 			``,
 			`
-			CREATE OR REPLACE VIEW V2 SQL SECURITY INVOKER AS SELECT * FROM T1;
-			CREATE OR REPLACE VIEW V1 SQL SECURITY INVOKER AS SELECT * FROM V2;`,
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
 			`
-			CREATE OR REPLACE VIEW V2 SQL SECURITY INVOKER AS SELECT * FROM T1;
-			CREATE OR REPLACE VIEW V1 SQL SECURITY INVOKER AS SELECT * FROM V2;`,
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
 			false,
 		},
-		"issue #37": { // https://github.com/morikuni/spannerdiff/issues/37
+		"drop view": {
 			`
-			CREATE INDEX IDX1 ON T1 (T1_I1)`,
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			``,
 			`
-			CREATE INDEX IDX1 ON T1 (T1_I1 ASC)`,
+			DROP VIEW V1;`,
+			false,
+		},
+		"view or replace": {
+			`
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			`
+			CREATE OR REPLACE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
 			``,
 			false,
 		},
-	} {
-		t.Run(name, func(t *testing.T) {
-			var buf bytes.Buffer
-			err := Diff(strings.NewReader(tt.base), strings.NewReader(tt.target), &buf, DiffOption{
-				ErrorOnUnsupportedDDL: true,
-			})
-			if tt.wantError {
-				if err == nil {
-					t.Fatalf("want error, got nil")
-				}
-				return
-			} else if err != nil {
-				t.Fatalf("want no error, got %v", err)
-			}
-
-			if (err != nil) != tt.wantError {
-				t.Fatalf("want error %v, got %v", tt.wantError, err)
-			}
-			equalDDLs(t, tt.wantDDLs, buf.String())
-		})
-	}
-}
-
-func equalDDLs(t *testing.T, a, b string) {
-	t.Helper()
+		"reformatted view": {
+			`
+			CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT a.T1_I1 FROM T1 AS a WHERE a.T1_I1>1;`,
+			`
+			CREATE VIEW V1
+			SQL SECURITY INVOKER
+			AS select a.T1_I1
+			  from T1 as a
+			  where a.T1_I1 > 1;`,
+			``,
+			false,
+		},
+		"recreate view": {
+			`
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			`
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1 WHERE T1_I1 > 0;`,
+			`
+			CREATE OR REPLACE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1 WHERE T1_I1 > 0;`,
+			false,
+		},
+		"replace view column list": {
+			`
+			CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT T1_I1 AS A FROM T1;`,
+			`
+			CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT T1_I1 AS A, T1_S1 AS B FROM T1;`,
+			`
+			CREATE OR REPLACE VIEW V1 SQL SECURITY INVOKER AS SELECT T1_I1 AS A, T1_S1 AS B FROM T1;`,
+			false,
+		},
+		"drop and create view": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1);
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			`
+			CREATE TABLE T1 (
+			  T1_S1 STRING(MAX) NOT NULL,
+			) PRIMARY KEY(T1_S1);
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			`
+			DROP VIEW V1;
+			DROP TABLE T1;
+			CREATE TABLE T1 (
+			  T1_S1 STRING(MAX) NOT NULL,
+			) PRIMARY KEY(T1_S1);
+			CREATE VIEW V1 SQL SECURITY DEFINER AS SELECT * FROM T1;`,
+			false,
+		},
+		"add change stream": {
+			``,
+			`
+			CREATE CHANGE STREAM S1 FOR ALL;`,
+			`
+			CREATE CHANGE STREAM S1 FOR ALL;`,
+			false,
+		},
+		"drop change stream": {
+			`
+			CREATE CHANGE STREAM S1 FOR ALL;`,
+			``,
+			`
+			DROP CHANGE STREAM S1;`,
+			false,
+		},
+		"alter change stream": {
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '36h' );`,
+			`
+			CREATE CHANGE STREAM S1 FOR T1(T1_I1) OPTIONS ( retention_period = '72h' );`,
+			`
+			ALTER CHANGE STREAM S1 SET FOR T1(T1_I1);
+			ALTER CHANGE STREAM S1 SET OPTIONS ( retention_period = '72h' );`,
+			false,
+		},
+		"alter change stream exclude ttl deletes": {
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '36h' );`,
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '36h', exclude_ttl_deletes = true );`,
+			`
+			ALTER CHANGE STREAM S1 SET OPTIONS ( retention_period = '36h', exclude_ttl_deletes = true );`,
+			false,
+		},
+		"alter change stream include ttl deletes": {
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '36h', exclude_ttl_deletes = true, exclude_delete = true );`,
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '36h', exclude_delete = true );`,
+			`
+			ALTER CHANGE STREAM S1 SET OPTIONS ( retention_period = '36h', exclude_delete = true, exclude_ttl_deletes = NULL );`,
+			false,
+		},
+		"recreate change stream on non-alterable option": {
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '36h', partition_mode = 'IMMUTABLE_KEY_RANGE' );`,
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '72h', partition_mode = 'MUTABLE_KEY_RANGE' );`,
+			`
+			DROP CHANGE STREAM S1;
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( retention_period = '72h', partition_mode = 'MUTABLE_KEY_RANGE' );`,
+			false,
+		},
+		"alter change stream on unknown option": {
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( foo = 1 );`,
+			`
+			CREATE CHANGE STREAM S1 FOR ALL OPTIONS ( foo = 2 );`,
+			`
+			ALTER CHANGE STREAM S1 SET OPTIONS ( foo = 2 );`,
+			false,
+		},
+		"alter column on unknown option": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 OPTIONS (foo = 1),
+			) PRIMARY KEY(T1_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 OPTIONS (foo = 2),
+			) PRIMARY KEY(T1_I1);`,
+			`
+			ALTER TABLE T1 ALTER COLUMN T1_I2 SET OPTIONS (foo = 2);`,
+			false,
+		},
+		"alter table on unknown option": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1), OPTIONS (foo = 1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1), OPTIONS (foo = 2);`,
+			`
+			ALTER TABLE T1 SET OPTIONS (foo = 2);`,
+			false,
+		},
+		"alter change stream from all to table columns": {
+			`
+			CREATE CHANGE STREAM S1 FOR ALL;`,
+			`
+			CREATE CHANGE STREAM S1 FOR T1(T1_S1);`,
+			`
+			ALTER CHANGE STREAM S1 SET FOR T1(T1_S1);`,
+			false,
+		},
+		"alter change stream from table to all": {
+			`
+			CREATE CHANGE STREAM S1 FOR T1, T2(T2_S1);`,
+			`
+			CREATE CHANGE STREAM S1 FOR ALL;`,
+			`
+			ALTER CHANGE STREAM S1 SET FOR ALL;`,
+			false,
+		},
+		"alter change stream drop for all": {
+			`
+			CREATE CHANGE STREAM S1 FOR ALL;`,
+			`
+			CREATE CHANGE STREAM S1;`,
+			`
+			ALTER CHANGE STREAM S1 DROP FOR ALL;`,
+			false,
+		},
+		"alter change stream add table": {
+			`
+			CREATE CHANGE STREAM S1 FOR T1, T2(T2_S1);`,
+			`
+			CREATE CHANGE STREAM S1 FOR T1, T2(T2_S1), T3;`,
+			`
+			ALTER CHANGE STREAM S1 SET FOR T1, T2(T2_S1), T3;`,
+			false,
+		},
+		"alter change stream remove table": {
+			`
+			CREATE CHANGE STREAM S1 FOR T1, T2(T2_S1), T3;`,
+			`
+			CREATE CHANGE STREAM S1 FOR T1, T3;`,
+			`
+			ALTER CHANGE STREAM S1 SET FOR T1, T3;`,
+			false,
+		},
+		"change stream tables reordered": {
+			`
+			CREATE CHANGE STREAM S1 FOR T1, T2(T2_S1, T2_S2);`,
+			`
+			CREATE CHANGE STREAM S1 FOR T2(T2_S2, T2_S1), T1;`,
+			``,
+			false,
+		},
+		"alter change stream table to primary key only": {
+			`
+			CREATE CHANGE STREAM S1 FOR T1;`,
+			`
+			CREATE CHANGE STREAM S1 FOR T1();`,
+			`
+			ALTER CHANGE STREAM S1 SET FOR T1();`,
+			false,
+		},
+		"add sequence": {
+			``,
+			`
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');`,
+			`
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');`,
+			false,
+		},
+		"drop sequence": {
+			`
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');`,
+			``,
+			`
+			DROP SEQUENCE S1;`,
+			false,
+		},
+		"drop sequence used by column default": {
+			`
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64 DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE S1)),
+			  T1_I3 INT64 DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE S1)),
+			) PRIMARY KEY(T1_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_I2 INT64,
+			) PRIMARY KEY(T1_I1);`,
+			`
+			ALTER TABLE T1 DROP COLUMN T1_I3;
+			ALTER TABLE T1 ALTER COLUMN T1_I2 DROP DEFAULT;
+			DROP SEQUENCE S1;`,
+			false,
+		},
+		"recreate sequence used by column defaults in multiple tables": {
+			`
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE S1)),
+			) PRIMARY KEY(T1_I1);
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_I2 INT64 DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE S1)),
+			) PRIMARY KEY(T2_I1);`,
+			`
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'default');
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE S1)),
+			) PRIMARY KEY(T1_I1);
+			CREATE TABLE T2 (
+			  T2_I1 INT64 NOT NULL,
+			  T2_I2 INT64 DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE S1)),
+			) PRIMARY KEY(T2_I1);`,
+			`
+			ALTER TABLE T2 ALTER COLUMN T2_I2 DROP DEFAULT;
+			ALTER TABLE T1 ALTER COLUMN T1_I1 DROP DEFAULT;
+			DROP SEQUENCE S1;
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'default');
+			ALTER TABLE T1 ALTER COLUMN T1_I1 SET DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE S1));
+			ALTER TABLE T2 ALTER COLUMN T2_I2 SET DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE S1));`,
+			false,
+		},
+		"alter sequence": {
+			`
+			CREATE SEQUENCE S1 OPTIONS (skip_range_min = 1000, skip_range_max = 2000);`,
+			`
+			CREATE SEQUENCE S1 OPTIONS (start_counter_with = 10);`,
+			`
+			ALTER SEQUENCE S1 SET OPTIONS (start_counter_with = 10);`,
+			false,
+		},
+		"equivalent numeric sequence option": {
+			`
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive', start_counter_with = 16);`,
+			`
+			CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive', start_counter_with = 0x10);`,
+			``,
+			false,
+		},
+		"add model": {
+			``,
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			false,
+		},
+		"drop model": {
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			``,
+			`
+			DROP MODEL M1;`,
+			false,
+		},
+		"alter model": {
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model2' );`,
+			`
+			ALTER MODEL M1 SET OPTIONS ( endpoint = 'model2' );`,
+			false,
+		},
+		"reorder model endpoints": {
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoints = ['model1', 'model2'] );`,
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoints = ['model2', 'model1'] );`,
+			``,
+			false,
+		},
+		"model or replace": {
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			`
+			CREATE OR REPLACE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			``,
+			false,
+		},
+		"property graph or replace": {
+			`
+			CREATE PROPERTY GRAPH G1 NODE TABLES (T1, T2);`,
+			`
+			CREATE OR REPLACE PROPERTY GRAPH G1 NODE TABLES (T1, T2);`,
+			``,
+			false,
+		},
+		"recreate model": {
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F3 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			`
+			CREATE OR REPLACE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F3 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			false,
+		},
+		"recreate model on column option": {
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model' );`,
+			`
+			CREATE MODEL M1 INPUT (F1 FLOAT64 OPTIONS (required = false)) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model2' );`,
+			`
+			CREATE OR REPLACE MODEL M1 INPUT (F1 FLOAT64 OPTIONS (required = false)) OUTPUT (F2 FLOAT64) REMOTE OPTIONS ( endpoint = 'model2' );`,
+			false,
+		},
+		"add proto bundle": {
+			``,
+			"CREATE PROTO BUNDLE (`test.proto`)",
+			"CREATE PROTO BUNDLE (`test.proto`)",
+			false,
+		},
+		"drop proto bundle": {
+			"CREATE PROTO BUNDLE (`test.proto`)",
+			``,
+			"DROP PROTO BUNDLE",
+			false,
+		},
+		"alter proto bundle": {
+			"CREATE PROTO BUNDLE (`test.proto`)",
+			"CREATE PROTO BUNDLE (`test2.proto`)",
+			"ALTER PROTO BUNDLE INSERT (`test2.proto`) DELETE (`test.proto`)",
+			false,
+		},
+		"proto bundle twice": {
+			"CREATE PROTO BUNDLE (`test.proto`); CREATE PROTO BUNDLE (`test2.proto`)",
+			"CREATE PROTO BUNDLE (`test.proto`); CREATE PROTO BUNDLE (`test2.proto`)",
+			"",
+			true,
+		},
+		"add role": {
+			``,
+			`
+			CREATE ROLE R1;`,
+			`CREATE ROLE R1;`,
+			false,
+		},
+		"drop role": {
+			`
+			CREATE ROLE R1;`,
+			``,
+			`
+			DROP ROLE R1;`,
+			false,
+		},
+		"add table grant": {
+			``,
+			`
+			GRANT SELECT, UPDATE ON TABLE T1 TO ROLE R1;`,
+			`
+			GRANT SELECT, UPDATE ON TABLE T1 TO ROLE R1;`,
+			false,
+		},
+		"drop table grant": {
+			`
+			GRANT SELECT, UPDATE ON TABLE T1 TO ROLE R1;`,
+			``,
+			`
+			REVOKE SELECT, UPDATE ON TABLE T1 FROM ROLE R1;`,
+			false,
+		},
+		"alter table grant": {
+			`
+			GRANT SELECT, SELECT(T1_C1), UPDATE, INSERT(T1_C1, T1_C2) ON TABLE T1 TO ROLE R1;
+			GRANT UPDATE, DELETE ON TABLE T1 TO ROLE R2;`,
+			`
+			GRANT SELECT(T1_C2), DELETE ON TABLE T1 TO ROLE R1;
+			GRANT SELECT, UPDATE(T1_C1, T1_C2), UPDATE, INSERT ON TABLE T1 TO ROLE R2;`,
+			`
+			REVOKE SELECT, SELECT(T1_C1), UPDATE, INSERT(T1_C1, T1_C2) ON TABLE T1 FROM ROLE R1;
+			GRANT SELECT(T1_C2), DELETE ON TABLE T1 TO ROLE R1;
+			REVOKE DELETE ON TABLE T1 FROM ROLE R2;
+			GRANT SELECT, UPDATE(T1_C1, T1_C2), INSERT ON TABLE T1 TO ROLE R2;`,
+			false,
+		},
+		"reorder table grant privileges": {
+			`
+			GRANT SELECT, UPDATE, DELETE ON TABLE T1 TO ROLE R1;`,
+			`
+			GRANT DELETE, UPDATE, SELECT ON TABLE T1 TO ROLE R1;`,
+			``,
+			false,
+		},
+		"reorder table grant columns": {
+			`
+			GRANT SELECT(T1_I1, T1_S1), UPDATE(T1_I1, T1_S1) ON TABLE T1 TO ROLE R1;`,
+			`
+			GRANT SELECT(T1_S1, T1_I1), UPDATE(T1_S1, T1_I1) ON TABLE T1 TO ROLE R1;`,
+			``,
+			false,
+		},
+		"reorder table grants": {
+			`
+			GRANT SELECT(T1_I1, T1_S1), INSERT ON TABLE T1 TO ROLE R1;
+			GRANT UPDATE(T1_S1) ON TABLE T1 TO ROLE R1;
+			GRANT SELECT(T1_I2) ON TABLE T1 TO ROLE R1;
+			GRANT SELECT ON TABLE T2 TO ROLE R1, R2;`,
+			`
+			GRANT SELECT ON TABLE T2 TO ROLE R2, R1;
+			GRANT SELECT(T1_I2, T1_S1) ON TABLE T1 TO ROLE R1;
+			GRANT INSERT, UPDATE(T1_S1), SELECT(T1_I1) ON TABLE T1 TO ROLE R1;`,
+			``,
+			false,
+		},
+		"narrow multi-table grant": {
+			`
+			GRANT SELECT, INSERT ON TABLE T1, T2 TO ROLE R1;`,
+			`
+			GRANT SELECT, INSERT ON TABLE T1 TO ROLE R1;`,
+			`
+			REVOKE SELECT, INSERT ON TABLE T2 FROM ROLE R1;`,
+			false,
+		},
+		"narrow multi-table grant to multiple roles": {
+			`
+			GRANT SELECT ON TABLE T1, T2 TO ROLE R1, R2;`,
+			`
+			GRANT SELECT ON TABLE T1 TO ROLE R1, R2;
+			GRANT SELECT ON TABLE T2 TO ROLE R2;`,
+			`
+			REVOKE SELECT ON TABLE T2 FROM ROLE R1;`,
+			false,
+		},
+		"revoke table-wide select keeping column select": {
+			`
+			GRANT SELECT, SELECT(T1_S1) ON TABLE T1 TO ROLE R1;`,
+			`
+			GRANT SELECT(T1_S1) ON TABLE T1 TO ROLE R1;`,
+			`
+			REVOKE SELECT ON TABLE T1 FROM ROLE R1;`,
+			false,
+		},
+		"revoke table-wide select keeping column select in other statement": {
+			`
+			GRANT SELECT ON TABLE T1 TO ROLE R1;
+			GRANT SELECT(T1_S1) ON TABLE T1 TO ROLE R1;`,
+			`
+			GRANT SELECT(T1_S1) ON TABLE T1 TO ROLE R1;`,
+			`
+			REVOKE SELECT ON TABLE T1 FROM ROLE R1;`,
+			false,
+		},
+		"revoke column select keeping table-wide select": {
+			`
+			GRANT SELECT, SELECT(T1_S1) ON TABLE T1 TO ROLE R1;`,
+			`
+			GRANT SELECT ON TABLE T1 TO ROLE R1;`,
+			`
+			REVOKE SELECT(T1_S1) ON TABLE T1 FROM ROLE R1;`,
+			false,
+		},
+		"add view grant": {
+			``,
+			`
+			GRANT SELECT ON VIEW V1 TO ROLE R1;`,
+			`
+			GRANT SELECT ON VIEW V1 TO ROLE R1;`,
+			false,
+		},
+		"drop view grant": {
+			`
+			GRANT SELECT ON VIEW V1 TO ROLE R1;`,
+			``,
+			`
+			REVOKE SELECT ON VIEW V1 FROM ROLE R1;`,
+			false,
+		},
+		"change grant from view to table": {
+			`
+			CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT 1 AS C1;
+			GRANT SELECT ON VIEW V1 TO ROLE R1;`,
+			`
+			CREATE TABLE V1 (
+			  C1 INT64 NOT NULL,
+			) PRIMARY KEY(C1);
+			GRANT SELECT ON TABLE V1 TO ROLE R1;`,
+			`
+			REVOKE SELECT ON VIEW V1 FROM ROLE R1;
+			DROP VIEW V1;
+			CREATE TABLE V1 (
+			  C1 INT64 NOT NULL,
+			) PRIMARY KEY(C1);
+			GRANT SELECT ON TABLE V1 TO ROLE R1;`,
+			false,
+		},
+		"add change stream grant": {
+			``,
+			`
+			GRANT SELECT ON CHANGE STREAM S1 TO ROLE R1;`,
+			`
+			GRANT SELECT ON CHANGE STREAM S1 TO ROLE R1;`,
+			false,
+		},
+		"drop change stream grant": {
+			`
+			GRANT SELECT ON CHANGE STREAM S1 TO ROLE R1;`,
+			``,
+			`
+			REVOKE SELECT ON CHANGE STREAM S1 FROM ROLE R1;`,
+			false,
+		},
+		"add table function grant": {
+			``,
+			`
+			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			`
+			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			false,
+		},
+		"drop table function grant": {
+			`
+			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			``,
+			`
+			REVOKE EXECUTE ON TABLE FUNCTION READ_CS1 FROM ROLE R1;`,
+			false,
+		},
+		"recreate table function grant with change stream": {
+			`
+			CREATE CHANGE STREAM CS1 FOR ALL OPTIONS ( partition_mode = 'IMMUTABLE_KEY_RANGE' );
+			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			`
+			CREATE CHANGE STREAM CS1 FOR ALL OPTIONS ( partition_mode = 'MUTABLE_KEY_RANGE' );
+			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			`
+			REVOKE EXECUTE ON TABLE FUNCTION READ_CS1 FROM ROLE R1;
+			DROP CHANGE STREAM CS1;
+			CREATE CHANGE STREAM CS1 FOR ALL OPTIONS ( partition_mode = 'MUTABLE_KEY_RANGE' );
+			GRANT EXECUTE ON TABLE FUNCTION READ_CS1 TO ROLE R1;`,
+			false,
+		},
+		"add role grant": {
+			``,
+			`
+			GRANT ROLE R2 TO ROLE R1;`,
+			`
+			GRANT ROLE R2 TO ROLE R1;`,
+			false,
+		},
+		"rename role with table grants": {
+			`
+			CREATE ROLE R1;
+			GRANT SELECT ON TABLE T1 TO ROLE R1;`,
+			`
+			CREATE ROLE R2;
+			GRANT SELECT ON TABLE T1 TO ROLE R2;`,
+			`
+			REVOKE SELECT ON TABLE T1 FROM ROLE R1;
+			DROP ROLE R1;
+			CREATE ROLE R2;
+			GRANT SELECT ON TABLE T1 TO ROLE R2;`,
+			false,
+		},
+		"drop role grant": {
+			`
+			GRANT ROLE R2 TO ROLE R1;`,
+			``,
+			`
+			REVOKE ROLE R2 FROM ROLE R1;`,
+			false,
+		},
+		"add alter database": {
+			``,
+			`
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d');`,
+			`
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d');`,
+			false,
+		},
+		"no drop alter database": {
+			`
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d');`,
+			``,
+			``,
+			false,
+		},
+		"alter alter database": {
+			`
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d', optimizer_version = 1);`,
+			`
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '2d');`,
+			`
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '2d');`,
+			false,
+		},
+		"merge alter database": {
+			`
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d', optimizer_version = 1);`,
+			`
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '2d');
+			ALTER DATABASE D1 SET OPTIONS (version_retention_period = '1d', optimizer_version = 1);`,
+			``,
+			false,
+		},
+		"equivalent numeric database option": {
+			`
+			ALTER DATABASE D1 SET OPTIONS (optimizer_version = 1);`,
+			`
+			ALTER DATABASE D1 SET OPTIONS (optimizer_version = 1.0);`,
+			``,
+			false,
+		},
+		"alter numeric database option with different representation": {
+			`
+			ALTER DATABASE D1 SET OPTIONS (optimizer_version = 1);`,
+			`
+			ALTER DATABASE D1 SET OPTIONS (optimizer_version = 2.0);`,
+			`
+			ALTER DATABASE D1 SET OPTIONS (optimizer_version = 2.0);`,
+			false,
+		},
+		"add locality group": {
+			``,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX) OPTIONS (locality_group = 'LG2'),
+			) PRIMARY KEY(T1_I1), OPTIONS (locality_group = 'LG1');
+			CREATE LOCALITY GROUP LG1 OPTIONS (storage = 'ssd');
+			CREATE LOCALITY GROUP LG2 OPTIONS (storage = 'hdd');`,
+			`
+			CREATE LOCALITY GROUP LG1 OPTIONS (storage = 'ssd');
+			CREATE LOCALITY GROUP LG2 OPTIONS (storage = 'hdd');
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX) OPTIONS (locality_group = 'LG2'),
+			) PRIMARY KEY(T1_I1), OPTIONS (locality_group = 'LG1');`,
+			false,
+		},
+		"drop locality group": {
+			`
+			CREATE LOCALITY GROUP LG1 OPTIONS (storage = 'ssd');`,
+			``,
+			`
+			DROP LOCALITY GROUP LG1;`,
+			false,
+		},
+		"alter locality group": {
+			`
+			CREATE LOCALITY GROUP LG1 OPTIONS (storage = 'ssd', ssd_to_hdd_spill_timespan = '10d');`,
+			`
+			CREATE LOCALITY GROUP LG1 OPTIONS (storage = 'hdd');`,
+			`
+			ALTER LOCALITY GROUP LG1 SET OPTIONS (storage = 'hdd', ssd_to_hdd_spill_timespan = NULL);`,
+			false,
+		},
+		"alter locality group referenced by table and column": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX) OPTIONS (locality_group = 'LG1'),
+			) PRIMARY KEY(T1_I1), OPTIONS (locality_group = 'LG1');
+			CREATE LOCALITY GROUP LG1 OPTIONS (storage = 'ssd', foo = 1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX) OPTIONS (locality_group = 'LG1'),
+			) PRIMARY KEY(T1_I1), OPTIONS (locality_group = 'LG1');
+			CREATE LOCALITY GROUP LG1 OPTIONS (storage = 'hdd', foo = 2);`,
+			`
+			ALTER LOCALITY GROUP LG1 SET OPTIONS (storage = 'hdd', foo = 2);`,
+			false,
+		},
+		"alter table locality group": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1), OPTIONS (locality_group = 'LG1');`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1), OPTIONS (locality_group = 'LG2');
+			CREATE LOCALITY GROUP LG2;`,
+			`
+			CREATE LOCALITY GROUP LG2;
+			ALTER TABLE T1 SET OPTIONS (locality_group = 'LG2');`,
+			false,
+		},
+		"issue #35": { // https://github.com/morikuni/spannerdiff/issues/35
+			``,
+			`
+			CREATE OR REPLACE VIEW V2 SQL SECURITY INVOKER AS SELECT * FROM T1;
+			CREATE OR REPLACE VIEW V1 SQL SECURITY INVOKER AS SELECT * FROM V2;`,
+			`
+			CREATE OR REPLACE VIEW V2 SQL SECURITY INVOKER AS SELECT * FROM T1;
+			CREATE OR REPLACE VIEW V1 SQL SECURITY INVOKER AS SELECT * FROM V2;`,
+			false,
+		},
+		"issue #37": { // https://github.com/morikuni/spannerdiff/issues/37
+			`
+			CREATE INDEX IDX1 ON T1 (T1_I1)`,
+			`
+			CREATE INDEX IDX1 ON T1 (T1_I1 ASC)`,
+			``,
+			false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Diff(strings.NewReader(tt.base), strings.NewReader(tt.target), &buf, DiffOption{
+				ErrorOnUnsupportedDDL: true,
+			})
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("want error, got nil")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if (err != nil) != tt.wantError {
+				t.Fatalf("want error %v, got %v", tt.wantError, err)
+			}
+			equalDDLs(t, tt.wantDDLs, buf.String())
+		})
+	}
+}
+
+func equalDDLs(t *testing.T, a, b string) {
+	t.Helper()
 	ddlsA, err := memefish.ParseDDLs("a", a)
 	if err != nil {
-		t.Fatalf("failed to parse ddl a: %v", err)
+		t.Fatalf("failed to parse ddl a: %v", err)
+	}
+	ddlsB, err := memefish.ParseDDLs("b", b)
+	if err != nil {
+		t.Fatalf("failed to parse ddl b: %v", err)
+	}
+	linesA := make([]string, 0, len(ddlsA))
+	for _, ddl := range ddlsA {
+		linesA = append(linesA, ddl.SQL())
+	}
+	linesB := make([]string, 0, len(ddlsB))
+	for _, ddl := range ddlsB {
+		linesB = append(linesB, ddl.SQL())
+	}
+	if diff := cmp.Diff(linesA, linesB); diff != "" {
+		t.Errorf("diff (+got -want):\n%s", diff)
+	}
+}
+
+func TestWithLineNumbers(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(``), strings.NewReader(`
+		CREATE SCHEMA S1;
+		CREATE SCHEMA S2;`), &buf, DiffOption{
+		Printer: WithSpacer("\n", WithLineNumbers(NoStylePrinter{})),
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := "-- 1/2\nCREATE SCHEMA S1;\n\n-- 2/2\nCREATE SCHEMA S2;\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_SchemaRename(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(`
+		CREATE SCHEMA S1;
+		CREATE TABLE S1.T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE TABLE S1.T2 (
+		  T2_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T2_I1);
+		CREATE INDEX S1.IDX1 ON S1.T1 (T1_I1);
+		CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT T1.T1_I1 FROM S1.T1 AS T1;`), strings.NewReader(`
+		CREATE SCHEMA S2;
+		CREATE TABLE S2.T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX S2.IDX1 ON S2.T1 (T1_I1);
+		CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT T1.T1_I1 FROM S2.T1 AS T1;`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		SchemaRename:          map[string]string{"S1": "S2"},
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		DROP VIEW V1;
+		DROP TABLE S1.T2;
+		DROP INDEX S1.IDX1;
+		DROP TABLE S1.T1;
+		DROP SCHEMA S1;
+		CREATE SCHEMA S2;
+		CREATE TABLE S2.T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX S2.IDX1 ON S2.T1 (T1_I1);
+		CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT T1.T1_I1 FROM S2.T1 AS T1;`, buf.String())
+	want := []string{
+		"Index(S2.IDX1) is moved from schema S1, but Spanner can't move objects between schemas, so it is dropped and created again",
+		"Table(S2.T1) is moved from schema S1, but Spanner can't move objects between schemas, so it is dropped and created again",
+	}
+	if diff := cmp.Diff(want, result.Warnings); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_DefaultSchema(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE SD.T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX SD.IDX1 ON SD.T1 (T1_I1);
+		CREATE SEQUENCE SD.SEQ1 OPTIONS (sequence_kind = 'bit_reversed_positive');
+		CREATE VIEW SD.V1 SQL SECURITY INVOKER AS SELECT T1_I1 FROM T1;`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX IDX1 ON T1 (T1_I1);
+		CREATE SEQUENCE SEQ1 OPTIONS (sequence_kind = 'bit_reversed_positive');
+		CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT T1_I1 FROM T1;`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		DefaultSchema:         "SD",
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, ``, buf.String())
+}
+
+func TestDiff_DefaultSchema_ViewQuery(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE SD.T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE VIEW SD.V1 SQL SECURITY INVOKER AS SELECT T1_I1 FROM SD.T1;`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT T1_I1 FROM SD.T1 WHERE T1_I1 > 0;`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		DefaultSchema:         "SD",
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	// The names in the query are kept as written.
+	equalDDLs(t, `
+		CREATE OR REPLACE VIEW V1 SQL SECURITY INVOKER AS SELECT T1_I1 FROM SD.T1 WHERE T1_I1 > 0;`, buf.String())
+}
+
+func TestDiff_QualifySchema(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX IDX1 ON T1 (T1_S1);
+		CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT T1_I1 FROM T1;`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE TABLE T2 (
+		  T1_I1 INT64 NOT NULL,
+		  T2_I1 INT64 NOT NULL,
+		  CONSTRAINT FK1 FOREIGN KEY (T1_I1) REFERENCES T1 (T1_I1),
+		) PRIMARY KEY(T1_I1, T2_I1), INTERLEAVE IN PARENT T1;
+		CREATE INDEX IDX2 ON T2 (T2_I1);
+		CREATE VIEW V2 SQL SECURITY INVOKER AS SELECT T1_I1 FROM T1;`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		QualifySchema:         "SD",
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		DROP VIEW SD.V1;
+		DROP INDEX SD.IDX1;
+		ALTER TABLE SD.T1 DROP COLUMN T1_S1;
+		CREATE TABLE SD.T2 (
+		  T1_I1 INT64 NOT NULL,
+		  T2_I1 INT64 NOT NULL,
+		  CONSTRAINT FK1 FOREIGN KEY (T1_I1) REFERENCES SD.T1 (T1_I1),
+		) PRIMARY KEY(T1_I1, T2_I1), INTERLEAVE IN PARENT SD.T1;
+		CREATE INDEX SD.IDX2 ON SD.T2 (T2_I1);
+		CREATE VIEW SD.V2 SQL SECURITY INVOKER AS SELECT T1_I1 FROM T1;`, buf.String())
+}
+
+func TestDiff_OrderBy(t *testing.T) {
+	target := `
+		CREATE ROLE R1;
+		CREATE CHANGE STREAM CS1 FOR ALL;
+		CREATE INDEX IDX1 ON T1 (T1_I1);
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);`
+	for name, tt := range map[string]struct {
+		orderBy  OrderBy
+		wantDDLs string
+	}{
+		"id": {
+			OrderByID,
+			`
+			CREATE CHANGE STREAM CS1 FOR ALL;
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1 (T1_I1);
+			CREATE ROLE R1;`,
+		},
+		"type": {
+			OrderByType,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1 (T1_I1);
+			CREATE CHANGE STREAM CS1 FOR ALL;
+			CREATE ROLE R1;`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Diff(strings.NewReader(``), strings.NewReader(target), &buf, DiffOption{
+				ErrorOnUnsupportedDDL: true,
+				OrderBy:               tt.orderBy,
+			})
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			equalDDLs(t, tt.wantDDLs, buf.String())
+		})
+	}
+}
+
+func TestDiff_Result(t *testing.T) {
+	for name, tt := range map[string]struct {
+		base   string
+		target string
+		want   DiffResult
+	}{
+		"empty": {
+			``,
+			``,
+			DiffResult{Statements: 0, EmptySchemas: true},
+		},
+		"no differences": {
+			`CREATE SCHEMA S1;`,
+			`CREATE SCHEMA S1;`,
+			DiffResult{Statements: 0, EmptySchemas: false},
+		},
+		"differences": {
+			``,
+			`CREATE SCHEMA S1; CREATE SCHEMA S2;`,
+			DiffResult{Statements: 2, EmptySchemas: false},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DiffWithResult(strings.NewReader(tt.base), strings.NewReader(tt.target), &bytes.Buffer{}, DiffOption{
+				ErrorOnUnsupportedDDL: true,
+			})
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiff_SafeOnly(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		CREATE TABLE T2 (
+		  T2_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T2_I1);
+		CREATE INDEX IDX1 ON T2(T2_I1);`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_I2 INT64,
+		) PRIMARY KEY(T1_I1);
+		CREATE TABLE T2 (
+		  T2_I1 INT64 NOT NULL,
+		  T2_I2 INT64 NOT NULL,
+		) PRIMARY KEY(T2_I2);
+		CREATE INDEX IDX1 ON T2(T2_I1);`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		SafeOnly:              true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		ALTER TABLE T1 ADD COLUMN T1_I2 INT64;`, buf.String())
+	want := []string{
+		"drop Table(T1):Column(T1_S1)",
+		"drop_and_add Index(IDX1)",
+		"drop_and_add Table(T2)",
+	}
+	if diff := cmp.Diff(want, result.Skipped); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_WarnUndefinedReferences(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(`
+		CREATE INDEX IDX1 ON T1(T1_I1);
+		CREATE ROLE R1;
+		GRANT SELECT ON TABLE T2 TO ROLE R1;
+		GRANT ROLE spanner_info_reader TO ROLE R1;`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX IDX1 ON T1(T1_I1);
+		CREATE VIEW V1 SQL SECURITY INVOKER AS WITH W1 AS (SELECT T1_I1 FROM T1) SELECT W1.T1_I1 FROM W1 JOIN T3 ON TRUE;
+		CREATE ROLE R1;
+		GRANT SELECT ON VIEW V1 TO ROLE R2;`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL:   true,
+		WarnUndefinedReferences: true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := []string{
+		"base: Grant(Role(R1)):Table(T2) references undefined Table(T2)",
+		"base: Index(IDX1) references undefined Table(T1)",
+		"target: Grant(Role(R2)):View(V1) references undefined Role(R2)",
+		"target: View(V1) references undefined Table(T3)",
+	}
+	if diff := cmp.Diff(want, result.Warnings); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_MaxDrops(t *testing.T) {
+	base := `
+		CREATE ROLE R1;
+		CREATE ROLE R2;
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);`
+	target := `
+		CREATE ROLE R3;
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 INT64,
+		) PRIMARY KEY(T1_I1);`
+
+	var buf bytes.Buffer
+	limit := 2
+	err := Diff(strings.NewReader(base), strings.NewReader(target), &buf, DiffOption{MaxDrops: &limit})
+	var tde *TooManyDropsError
+	if !errors.As(err, &tde) {
+		t.Fatalf("want TooManyDropsError, got %v", err)
+	}
+	want := []string{
+		"drop Role(R1)",
+		"drop Role(R2)",
+		"drop_and_add Table(T1):Column(T1_S1)",
+	}
+	if diff := cmp.Diff(want, tde.Drops); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("want no output, got %q", buf.String())
+	}
+
+	limit = 3
+	if err := Diff(strings.NewReader(base), strings.NewReader(target), &buf, DiffOption{MaxDrops: &limit}); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+}
+
+func TestDiff_AdditiveOnly(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		  T1_TS1 TIMESTAMP,
+		) PRIMARY KEY(T1_I1), ROW DELETION POLICY (OLDER_THAN(T1_TS1, INTERVAL 1 DAY));
+		CREATE ROLE R1;
+		GRANT SELECT, INSERT ON TABLE T1 TO ROLE R1;`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_I2 INT64,
+		  T1_TS1 TIMESTAMP,
+		) PRIMARY KEY(T1_I1);
+		CREATE ROLE R1;
+		GRANT SELECT, UPDATE ON TABLE T1 TO ROLE R1;`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		AdditiveOnly:          true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		GRANT UPDATE ON TABLE T1 TO ROLE R1;
+		ALTER TABLE T1 ADD COLUMN T1_I2 INT64;`, buf.String())
+	want := []string{
+		"alter Grant(Role(R1)):Table(T1): REVOKE INSERT ON TABLE T1 FROM ROLE R1",
+		"alter Table(T1): ALTER TABLE T1 DROP ROW DELETION POLICY",
+		"drop Table(T1):Column(T1_S1)",
+	}
+	if diff := cmp.Diff(want, result.Skipped); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_IdempotentGrants(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE ROLE R1;
+		GRANT SELECT ON TABLE T1 TO ROLE R1;`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE ROLE R1;
+		CREATE ROLE R2;
+		GRANT SELECT, UPDATE ON TABLE T1 TO ROLE R1;
+		GRANT ROLE R1 TO ROLE R2;`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		IdempotentGrants:      true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		REVOKE UPDATE ON TABLE T1 FROM ROLE R1;
+		GRANT UPDATE ON TABLE T1 TO ROLE R1;
+		CREATE ROLE R2;
+		REVOKE ROLE R1 FROM ROLE R2;
+		GRANT ROLE R1 TO ROLE R2;`, buf.String())
+}
+
+func TestDiff_Ignore(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		CREATE TABLE Audit1 (
+		  Audit1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(Audit1_I1);
+		CREATE INDEX IDX1 ON Audit1(Audit1_I1);`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		Ignore:                []string{"Table(Audit*)"},
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		ALTER TABLE T1 ADD COLUMN T1_S1 STRING(MAX);`, buf.String())
+}
+
+func TestDiff_Error(t *testing.T) {
+	for name, tt := range map[string]struct {
+		base   string
+		target string
+		check  func(t *testing.T, err error)
+	}{
+		"parse error": {
+			``,
+			`CREATE TABLE`,
+			func(t *testing.T, err error) {
+				var pe *ParseError
+				if !errors.As(err, &pe) || pe.Which != "target" {
+					t.Errorf("want target ParseError, got %v", err)
+				}
+			},
+		},
+		"duplicate definition": {
+			`CREATE SCHEMA S2; CREATE SCHEMA S1; CREATE SCHEMA S1; CREATE SCHEMA S2;`,
+			``,
+			func(t *testing.T, err error) {
+				var de *DuplicateDefinitionError
+				if !errors.As(err, &de) {
+					t.Fatalf("want DuplicateDefinitionError, got %v", err)
+				}
+				if diff := cmp.Diff([]string{"Schema(S1)", "Schema(S2)"}, de.IDs); diff != "" {
+					t.Errorf("diff (-want +got):\n%s", diff)
+				}
+			},
+		},
+		"unsupported ddl": {
+			``,
+			`ALTER INDEX IDX1 ADD STORED COLUMN T1_I1;`,
+			func(t *testing.T, err error) {
+				var ue *UnsupportedDDLError
+				if !errors.As(err, &ue) {
+					t.Errorf("want UnsupportedDDLError, got %v", err)
+				}
+			},
+		},
+		"postgresql dialect": {
+			``,
+			`
+			CREATE TABLE t1 (
+			  id bigint NOT NULL,
+			  name character varying(1024),
+			  PRIMARY KEY(id)
+			);`,
+			func(t *testing.T, err error) {
+				var ue *UnsupportedDDLError
+				if !errors.As(err, &ue) || ue.Reason == "" {
+					t.Errorf("want UnsupportedDDLError with reason, got %v", err)
+				}
+			},
+		},
+		"reference to dropped definition": {
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_I1);`,
+			`
+			CREATE INDEX IDX1 ON T1(T1_I1);`,
+			func(t *testing.T, err error) {
+				var de *DanglingReferenceError
+				if !errors.As(err, &de) {
+					t.Fatalf("want DanglingReferenceError, got %v", err)
+				}
+				if diff := cmp.Diff([]string{"Index(IDX1) references Table(T1)"}, de.References); diff != "" {
+					t.Errorf("diff (-want +got):\n%s", diff)
+				}
+			},
+		},
+		"reference to dropped column": {
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL, T1_S1 STRING(MAX)) PRIMARY KEY (T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
+			`
+			CREATE TABLE T1 (T1_I1 INT64 NOT NULL) PRIMARY KEY (T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
+			func(t *testing.T, err error) {
+				var de *DanglingReferenceError
+				if !errors.As(err, &de) {
+					t.Fatalf("want DanglingReferenceError, got %v", err)
+				}
+				if diff := cmp.Diff([]string{"Index(IDX1) references Table(T1):Column(T1_S1)"}, de.References); diff != "" {
+					t.Errorf("diff (-want +got):\n%s", diff)
+				}
+			},
+		},
+		"dependency cycle": {
+			``,
+			`
+			CREATE VIEW V1 SQL SECURITY INVOKER AS SELECT * FROM V2;
+			CREATE VIEW V2 SQL SECURITY INVOKER AS SELECT * FROM V1;`,
+			func(t *testing.T, err error) {
+				if !errors.Is(err, ErrDependencyCycle) {
+					t.Errorf("want ErrDependencyCycle, got %v", err)
+				}
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := Diff(strings.NewReader(tt.base), strings.NewReader(tt.target), &bytes.Buffer{}, DiffOption{
+				ErrorOnUnsupportedDDL: true,
+			})
+			tt.check(t, err)
+		})
+	}
+}
+
+func TestDiff_AnnotateDrops(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX IDX1 ON T1(T1_I1);
+		GRANT SELECT ON TABLE T1 TO ROLE R1;`), strings.NewReader(``), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		AnnotateDrops:         true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
 	}
-	ddlsB, err := memefish.ParseDDLs("b", b)
+	want := "DROP INDEX IDX1;\n" +
+		"REVOKE SELECT ON TABLE T1 FROM ROLE R1;\n" +
+		"-- dependents dropped together: Grant(Role(R1)):Table(T1), Index(IDX1)\n" +
+		"DROP TABLE T1;\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_Hints(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		CREATE TABLE T2 (
+		  T2_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T2_I1);
+		CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'default');`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		Hints:                 true,
+	})
 	if err != nil {
-		t.Fatalf("failed to parse ddl b: %v", err)
+		t.Fatalf("want no error, got %v", err)
 	}
-	linesA := make([]string, 0, len(ddlsA))
-	for _, ddl := range ddlsA {
-		linesA = append(linesA, ddl.SQL())
+	want := "-- WARNING: data in T2 will be lost. Export it or take a backup before applying.\n" +
+		"DROP TABLE T2;\n" +
+		"-- WARNING: data in T1.T1_S1 will be lost. Export it or take a backup before applying.\n" +
+		"ALTER TABLE T1 DROP COLUMN T1_S1;\n" +
+		"-- WARNING: the counter of S1 will be lost. Check GET_INTERNAL_SEQUENCE_STATE before applying and set start_counter_with to continue it.\n" +
+		"DROP SEQUENCE S1;\n" +
+		"CREATE SEQUENCE S1 OPTIONS (sequence_kind = \"default\");\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
 	}
-	linesB := make([]string, 0, len(ddlsB))
-	for _, ddl := range ddlsB {
-		linesB = append(linesB, ddl.SQL())
+}
+
+func TestNewPrinter(t *testing.T) {
+	p, err := NewPrinter(ColorNever, nil, PrinterOptions{Spacer: "--\n"})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
 	}
-	if diff := cmp.Diff(linesA, linesB); diff != "" {
-		t.Errorf("diff (+got -want):\n%s", diff)
+	var buf bytes.Buffer
+	err = Diff(strings.NewReader(``), strings.NewReader(`
+		CREATE SCHEMA S1;
+		CREATE SCHEMA S2;`), &buf, DiffOption{Printer: p})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := "CREATE SCHEMA S1;\n--\nCREATE SCHEMA S2;\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+
+	if _, err := NewColorPrinter(PrinterOptions{Formatter: "unknown"}); err == nil {
+		t.Errorf("want error for unknown formatter, got nil")
+	}
+
+	for _, name := range ColorThemeNames() {
+		style, _ := ColorThemeStyle(name)
+		if _, err := NewColorPrinter(PrinterOptions{Style: style, Formatter: "terminal256"}); err != nil {
+			t.Errorf("want no error for theme %s, got %v", name, err)
+		}
+	}
+}
+
+func TestWithMaxWidth(t *testing.T) {
+	target := `
+		GRANT SELECT(T1_I1, T1_I2, T1_S1), INSERT(T1_I1, T1_I2) ON TABLE T1 TO ROLE R1;`
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(``), strings.NewReader(target), &buf, DiffOption{
+		Printer: WithMaxWidth(30, NoStylePrinter{}),
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := "GRANT SELECT(T1_I1, T1_I2,\n" +
+		"  T1_S1), INSERT(T1_I1,\n" +
+		"  T1_I2) ON TABLE T1 TO ROLE R1;\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+	equalDDLs(t, `GRANT SELECT(T1_I1, T1_I2, T1_S1), INSERT(T1_I1, T1_I2) ON TABLE T1 TO ROLE R1;`, buf.String())
+
+	if got := wrapLine(`  T1_S2 STRING(MAX) DEFAULT ("a, b, c, d, e, f"),`, 10); got != `  T1_S2 STRING(MAX) DEFAULT ("a, b, c, d, e, f"),` {
+		t.Errorf("want no wrap in string literal, got %q", got)
+	}
+}
+
+func TestDiff_TypeCase(t *testing.T) {
+	target := `
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX) DEFAULT ("INT64"),
+		  T1_A1 ARRAY<DATE>,
+		) PRIMARY KEY(T1_I1);`
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(``), strings.NewReader(target), &buf, DiffOption{
+		TypeCase: TypeCaseLower,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := "CREATE TABLE T1 (\n" +
+		"  T1_I1 int64 NOT NULL,\n" +
+		"  T1_S1 string(MAX) DEFAULT (\"INT64\"),\n" +
+		"  T1_A1 array<date>\n" +
+		") PRIMARY KEY (T1_I1);\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_WarnDestructive(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(`
+		CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'bit_reversed_positive');
+		CREATE SEQUENCE S2 OPTIONS (sequence_kind = 'bit_reversed_positive', skip_range_min = 1, skip_range_max = 1000);
+		CREATE MODEL M1 INPUT (F1 FLOAT64) OUTPUT (F2 FLOAT64) REMOTE OPTIONS (endpoint = 'model');`), strings.NewReader(`
+		CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'default');
+		CREATE SEQUENCE S2 OPTIONS (sequence_kind = 'bit_reversed_positive', skip_range_min = 1, skip_range_max = 2000);
+		CREATE MODEL M1 INPUT (F1 FLOAT64 OPTIONS (required = false)) OUTPUT (F2 FLOAT64) REMOTE OPTIONS (endpoint = 'model');`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		WarnDestructive:       true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		DROP SEQUENCE S1;
+		CREATE OR REPLACE MODEL M1 INPUT (F1 FLOAT64 OPTIONS (required = false)) OUTPUT (F2 FLOAT64) REMOTE OPTIONS (endpoint = 'model');
+		CREATE SEQUENCE S1 OPTIONS (sequence_kind = 'default');
+		ALTER SEQUENCE S2 SET OPTIONS (sequence_kind = 'bit_reversed_positive', skip_range_min = 1, skip_range_max = 2000);`, buf.String())
+	want := []string{
+		"Model(M1) is replaced to change its columns",
+		"Sequence(S1) is recreated and its counter is reset",
+	}
+	if diff := cmp.Diff(want, result.Warnings); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_WarnWriter(t *testing.T) {
+	var out, warn bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);`), strings.NewReader(`
+		CREATE INDEX IDX1 ON T2(T2_I1);`), &out, DiffOption{
+		ErrorOnUnsupportedDDL:   true,
+		WarnDestructive:         true,
+		WarnUndefinedReferences: true,
+		WarnWriter:              &warn,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		DROP TABLE T1;
+		CREATE INDEX IDX1 ON T2(T2_I1);`, out.String())
+	want := "warning: Table(T1) is dropped and its data is lost\n" +
+		"warning: target: Index(IDX1) references undefined Table(T2)\n"
+	if diff := cmp.Diff(want, warn.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenderCreate(t *testing.T) {
+	ddls, err := memefish.ParseDDLs("schema", `
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		GRANT SELECT ON TABLE T1 TO ROLE R1;
+		GRANT INSERT ON TABLE T1 TO ROLE R1;`)
+	if err != nil {
+		t.Fatalf("failed to parse ddl: %v", err)
+	}
+	for id, want := range map[string]string{
+		"Table(T1):Column(T1_S1)":   `ALTER TABLE T1 ADD COLUMN T1_S1 STRING(MAX)`,
+		"Grant(Role(R1)):Table(T1)": `GRANT SELECT, INSERT ON TABLE T1 TO ROLE R1`,
+	} {
+		got, err := RenderCreate(ddls, id)
+		if err != nil {
+			t.Fatalf("want no error, got %v", err)
+		}
+		equalDDLs(t, want, got)
+	}
+	if _, err := RenderCreate(ddls, "Table(T2)"); err == nil {
+		t.Errorf("want error for unknown definition, got nil")
+	}
+}
+
+func TestDump(t *testing.T) {
+	var buf bytes.Buffer
+	err := Dump(strings.NewReader(`
+		GRANT SELECT ON TABLE T1 TO ROLE R1;
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		GRANT INSERT ON TABLE T1 TO ROLE R1;
+		CREATE ROLE R1;`), &buf, DiffOption{})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		CREATE ROLE R1;
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		GRANT SELECT, INSERT ON TABLE T1 TO ROLE R1;`, buf.String())
+}
+
+func TestDiff_SplitBatches(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE ROLE R1;`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1 DESC);`), &buf, DiffOption{
+		SplitBatches: true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := "-- batch 1\n" +
+		"DROP TABLE T1;\n" +
+		"DROP ROLE R1;\n" +
+		"-- batch 2\n" +
+		"CREATE TABLE T1 (\n" +
+		"  T1_I1 INT64 NOT NULL\n" +
+		") PRIMARY KEY (T1_I1 DESC);\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+	if result.Batches != 2 {
+		t.Errorf("want 2 batches, got %d", result.Batches)
+	}
+}
+
+func TestDiff_Range(t *testing.T) {
+	target := `
+		CREATE ROLE R1;
+		CREATE ROLE R2;
+		CREATE ROLE R3;`
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(``), strings.NewReader(target), &buf, DiffOption{
+		Range: &StatementRange{From: 2, To: 3},
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		CREATE ROLE R2;
+		CREATE ROLE R3;`, buf.String())
+	if result.Statements != 3 {
+		t.Errorf("want 3 statements, got %d", result.Statements)
+	}
+
+	err = Diff(strings.NewReader(``), strings.NewReader(target), &buf, DiffOption{
+		Range: &StatementRange{From: 3, To: 4},
+	})
+	if err == nil {
+		t.Errorf("want error for out of range, got nil")
+	}
+
+	for _, s := range []string{"1", "a:2", "0:1", "2:1"} {
+		if _, err := ParseStatementRange(s); err == nil {
+			t.Errorf("want error for %q, got nil", s)
+		}
+	}
+}
+
+func TestPlanDDLs(t *testing.T) {
+	ddls, result, err := PlanDDLs(strings.NewReader(`
+		CREATE ROLE R1;`), strings.NewReader(`
+		CREATE ROLE R2;
+		CREATE ROLE R3;`), DiffOption{
+		Range: &StatementRange{From: 2, To: 3},
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := []string{
+		"CREATE ROLE R2",
+		"CREATE ROLE R3",
+	}
+	if diff := cmp.Diff(want, ddls); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+	if result.Statements != 3 {
+		t.Errorf("want 3 statements, got %d", result.Statements)
+	}
+}
+
+func TestPlanDDLs_Down(t *testing.T) {
+	for name, tt := range map[string]struct {
+		base     string
+		target   string
+		wantUp   []string
+		wantDown []string
+	}{
+		"add": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			) PRIMARY KEY(T1_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1);
+			CREATE INDEX IDX1 ON T1(T1_S1);`,
+			[]string{
+				"ALTER TABLE T1 ADD COLUMN T1_S1 STRING(MAX)",
+				"CREATE INDEX IDX1 ON T1(T1_S1)",
+			},
+			[]string{
+				"DROP INDEX IDX1",
+				"ALTER TABLE T1 DROP COLUMN T1_S1",
+			},
+		},
+		"alter": {
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(MAX),
+			) PRIMARY KEY(T1_I1);`,
+			`
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_S1 STRING(100) NOT NULL,
+			) PRIMARY KEY(T1_I1);`,
+			[]string{
+				"ALTER TABLE T1 ALTER COLUMN T1_S1 STRING(100) NOT NULL",
+			},
+			[]string{
+				"ALTER TABLE T1 ALTER COLUMN T1_S1 STRING(MAX)",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			up, _, err := PlanDDLs(strings.NewReader(tt.base), strings.NewReader(tt.target), DiffOption{})
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			if diff := cmp.Diff(tt.wantUp, up); diff != "" {
+				t.Errorf("up diff (-want +got):\n%s", diff)
+			}
+			down, _, err := PlanDDLs(strings.NewReader(tt.target), strings.NewReader(tt.base), DiffOption{})
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			if diff := cmp.Diff(tt.wantDown, down); diff != "" {
+				t.Errorf("down diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiff_FinalNewline(t *testing.T) {
+	for name, tt := range map[string]struct {
+		target       string
+		finalNewline FinalNewline
+		want         string
+	}{
+		"zero statements":      {``, FinalNewlineOne, ""},
+		"zero statements none": {``, FinalNewlineNone, ""},
+		"one statement":        {`CREATE ROLE R1;`, FinalNewlineOne, "CREATE ROLE R1;\n"},
+		"one statement none":   {`CREATE ROLE R1;`, FinalNewlineNone, "CREATE ROLE R1;"},
+		"many statements":      {`CREATE ROLE R1; CREATE ROLE R2;`, "", "CREATE ROLE R1;\n\nCREATE ROLE R2;\n"},
+		"many statements none": {`CREATE ROLE R1; CREATE ROLE R2;`, FinalNewlineNone, "CREATE ROLE R1;\n\nCREATE ROLE R2;"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Diff(strings.NewReader(``), strings.NewReader(tt.target), &buf, DiffOption{
+				Printer:      WithSpacer("\n", NoStylePrinter{}),
+				FinalNewline: tt.finalNewline,
+			})
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDiff_GroupByObject(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_I2 INT64,
+		  T1_I3 INT64,
+		) PRIMARY KEY(T1_I1);
+		CREATE TABLE T2 (
+		  T2_I1 INT64 NOT NULL,
+		  T2_I2 INT64,
+		) PRIMARY KEY(T2_I1);
+		CREATE INDEX IDX1 ON T1(T1_I3);`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		  T1_I3 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		CREATE TABLE T2 (
+		  T2_I1 INT64 NOT NULL,
+		  T2_S1 STRING(MAX),
+		) PRIMARY KEY(T2_I1);
+		CREATE INDEX IDX1 ON T1(T1_I3);`), &buf, DiffOption{
+		ErrorOnUnsupportedDDL: true,
+		GroupByObject:         true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	// The index is dropped before and created after recreating the column it depends on.
+	equalDDLs(t, `
+		ALTER TABLE T2 DROP COLUMN T2_I2;
+		ALTER TABLE T2 ADD COLUMN T2_S1 STRING(MAX);
+		ALTER TABLE T1 DROP COLUMN T1_I2;
+		ALTER TABLE T1 ADD COLUMN T1_S1 STRING(MAX);
+		DROP INDEX IDX1;
+		ALTER TABLE T1 DROP COLUMN T1_I3;
+		ALTER TABLE T1 ADD COLUMN T1_I3 STRING(MAX);
+		CREATE INDEX IDX1 ON T1(T1_I3);`, buf.String())
+}
+
+// testPlacementRole is a custom definition which creates a role shared by all placements.
+type testPlacementRole struct {
+	placement string
+}
+
+func (r testPlacementRole) ID() string { return "PlacementRole(" + r.placement + ")" }
+func (r testPlacementRole) Create() ast.DDL {
+	return &ast.CreateRole{Name: &ast.Ident{Name: "placement_admin"}}
+}
+func (r testPlacementRole) Drop() ast.DDL                     { return nil }
+func (r testPlacementRole) Alter(target Definition) []ast.DDL { return nil }
+func (r testPlacementRole) DependsOn() []string               { return nil }
+
+func TestDiff_Deduplicate(t *testing.T) {
+	defer func(handlers []customHandler) { customHandlers = handlers }(customHandlers)
+	RegisterDefinition(func(ddl ast.DDL) bool {
+		_, ok := ddl.(*ast.CreatePlacement)
+		return ok
+	}, func(ddl ast.DDL) []Definition {
+		p := ddl.(*ast.CreatePlacement)
+		return []Definition{testPlacement{p}, testPlacementRole{p.Name.Name}}
+	})
+
+	target := `
+		CREATE PLACEMENT P1 OPTIONS (instance_partition = "p1");
+		CREATE PLACEMENT P2 OPTIONS (instance_partition = "p2");`
+
+	// Custom definitions of both placements create the same role.
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(""), strings.NewReader(target), &buf, DiffOption{})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if result.Statements != 4 {
+		t.Errorf("want 4 statements, got %d: %s", result.Statements, buf.String())
+	}
+
+	buf.Reset()
+	result, err = DiffWithResult(strings.NewReader(""), strings.NewReader(target), &buf, DiffOption{
+		Deduplicate: true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		CREATE PLACEMENT P1 OPTIONS (instance_partition = "p1");
+		CREATE PLACEMENT P2 OPTIONS (instance_partition = "p2");
+		CREATE ROLE placement_admin;`, buf.String())
+	if result.Statements != 3 {
+		t.Errorf("want 3 statements, got %d", result.Statements)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	drop := func(name, comment string) operation {
+		return operation{
+			id:      newTableIDFromIdent(&ast.Ident{Name: name}),
+			kind:    operationKindDrop,
+			ddl:     &ast.DropTable{Name: &ast.Path{Idents: []*ast.Ident{{Name: name}}}},
+			comment: comment,
+		}
+	}
+	// Only adjacent duplicates are removed, and their comments are kept.
+	got := deduplicate([]operation{
+		drop("T1", "-- c1\n"),
+		drop("T1", "-- c2\n"),
+		drop("T1", "-- c1\n"),
+		drop("T2", ""),
+		drop("T1", ""),
+	})
+	var sqls []string
+	for _, op := range got {
+		sqls = append(sqls, op.comment+op.ddl.SQL())
+	}
+	want := []string{
+		"-- c1\n-- c2\nDROP TABLE T1",
+		"DROP TABLE T2",
+		"DROP TABLE T1",
+	}
+	if diff := cmp.Diff(want, sqls); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_Profile(t *testing.T) {
+	var buf, prof bytes.Buffer
+	err := Diff(strings.NewReader(``), strings.NewReader(`CREATE ROLE R1;`), &buf, DiffOption{
+		Profile: &prof,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	for _, phase := range []string{"parse: ", "definitions: ", "diff: ", "sort: "} {
+		if !strings.Contains(prof.String(), phase) {
+			t.Errorf("want %q in profile, got %q", phase, prof.String())
+		}
+	}
+}
+
+func TestDiff_SafeTypeChange(t *testing.T) {
+	var buf bytes.Buffer
+	err := Diff(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_I2 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_I2 FLOAT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);`), &buf, DiffOption{
+		SafeTypeChange: true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := "ALTER TABLE T1 ADD COLUMN T1_I2_new FLOAT64;\n" +
+		"-- backfill before dropping: UPDATE T1 SET T1_I2_new = CAST(T1_I2 AS FLOAT64) WHERE TRUE;\n" +
+		"ALTER TABLE T1 DROP COLUMN T1_I2;\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiff_EnumTypes(t *testing.T) {
+	table := func(typ string) string {
+		return `
+			CREATE TABLE T1 (
+			  T1_I1 INT64 NOT NULL,
+			  T1_C1 ` + typ + `,
+			) PRIMARY KEY(T1_I1)`
+	}
+	for name, tt := range map[string]struct {
+		base     string
+		target   string
+		wantDDLs string
+	}{
+		"proto to enum": {
+			table("`test.Proto`"),
+			table("`test.Enum`"),
+			`
+			ALTER TABLE T1 DROP COLUMN T1_C1;
+			ALTER TABLE T1 ADD COLUMN T1_C1 ` + "`test.Enum`" + `;`,
+		},
+		"enum to proto": {
+			table("`test.Enum`"),
+			table("`test.Proto`"),
+			`
+			ALTER TABLE T1 DROP COLUMN T1_C1;
+			ALTER TABLE T1 ADD COLUMN T1_C1 ` + "`test.Proto`" + `;`,
+		},
+		"proto to proto": {
+			table("`test.Proto`"),
+			table("`test.Proto2`"),
+			`
+			ALTER TABLE T1 ALTER COLUMN T1_C1 ` + "`test.Proto2`" + `;`,
+		},
+		"enum to enum": {
+			table("`test.Enum`"),
+			table("`test.Enum2`"),
+			`
+			ALTER TABLE T1 ALTER COLUMN T1_C1 ` + "`test.Enum2`" + `;`,
+		},
+		"int64 to enum": {
+			table("INT64"),
+			table("`test.Enum`"),
+			`
+			ALTER TABLE T1 ALTER COLUMN T1_C1 ` + "`test.Enum`" + `;`,
+		},
+		"enum to bytes": {
+			table("`test.Enum`"),
+			table("BYTES(MAX)"),
+			`
+			ALTER TABLE T1 DROP COLUMN T1_C1;
+			ALTER TABLE T1 ADD COLUMN T1_C1 BYTES(MAX);`,
+		},
+		"proto to bytes": {
+			table("`test.Proto`"),
+			table("BYTES(MAX)"),
+			`
+			ALTER TABLE T1 ALTER COLUMN T1_C1 BYTES(MAX);`,
+		},
+		"array of proto to array of enum": {
+			table("ARRAY<`test.Proto`>"),
+			table("ARRAY<`test.Enum`>"),
+			`
+			ALTER TABLE T1 DROP COLUMN T1_C1;
+			ALTER TABLE T1 ADD COLUMN T1_C1 ARRAY<` + "`test.Enum`" + `>;`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Diff(strings.NewReader(tt.base), strings.NewReader(tt.target), &buf, DiffOption{
+				EnumTypes: []string{"test.Enum", "test.Enum2"},
+				Printer:   NoStylePrinter{},
+			})
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			equalDDLs(t, tt.wantDDLs, buf.String())
+		})
+	}
+}
+
+func TestDiff_SafeTypeChange_Referenced(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := DiffWithResult(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_I2 INT64 NOT NULL,
+		  T1_I3 INT64 NOT NULL,
+		  CONSTRAINT CK1 CHECK (T1_I3 > 0),
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX IDX1 ON T1(T1_I2);`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_I2 FLOAT64 NOT NULL,
+		  T1_I3 FLOAT64 NOT NULL,
+		  CONSTRAINT CK1 CHECK (T1_I3 > 0),
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX IDX1 ON T1(T1_I2);`), &buf, DiffOption{
+		SafeTypeChange: true,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	equalDDLs(t, `
+		ALTER TABLE T1 DROP COLUMN T1_I3;
+		DROP INDEX IDX1;
+		ALTER TABLE T1 DROP COLUMN T1_I2;
+		ALTER TABLE T1 ADD COLUMN T1_I2 FLOAT64 NOT NULL;
+		CREATE INDEX IDX1 ON T1(T1_I2);
+		ALTER TABLE T1 ADD COLUMN T1_I3 FLOAT64 NOT NULL;`, buf.String())
+	want := []string{
+		"Table(T1):Column(T1_I2) is recreated without a temporary column because it is referenced by Index(IDX1)",
+		"Table(T1):Column(T1_I3) is recreated without a temporary column because it is referenced by Table(T1):Constraint(CK1)",
+	}
+	if diff := cmp.Diff(want, result.Warnings); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDroppedObjects(t *testing.T) {
+	dropped, err := DroppedObjects(strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 STRING(MAX),
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX IDX1 ON T1(T1_S1);
+		CREATE TABLE T2 (
+		  T2_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T2_I1);
+		CREATE ROLE R1;`), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		  T1_S1 INT64,
+		) PRIMARY KEY(T1_I1);
+		CREATE ROLE R1;`))
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	want := []string{
+		"Index(IDX1)",
+		"Table(T2)",
+		"Table(T2):Column(T2_I1)",
+	}
+	if diff := cmp.Diff(want, dropped); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnsupportedStatements(t *testing.T) {
+	ddls, err := memefish.ParseDDLs("schema", `
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		ALTER TABLE T1 ADD COLUMN T1_S1 STRING(MAX);
+		CREATE ROLE R1;
+		DROP ROLE R2;`)
+	if err != nil {
+		t.Fatalf("failed to parse ddl: %v", err)
+	}
+	var got []string
+	for _, ddl := range UnsupportedStatements(ddls) {
+		got = append(got, ddl.SQL())
+	}
+	want := []string{
+		"ALTER TABLE T1 ADD COLUMN T1_S1 STRING(MAX)",
+		"DROP ROLE R2",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+type testPlacement struct {
+	node *ast.CreatePlacement
+}
+
+func (p testPlacement) ID() string                        { return "Placement(" + p.node.Name.Name + ")" }
+func (p testPlacement) Create() ast.DDL                   { return p.node }
+func (p testPlacement) Drop() ast.DDL                     { return nil }
+func (p testPlacement) Alter(target Definition) []ast.DDL { return nil }
+func (p testPlacement) DependsOn() []string               { return nil }
+
+func TestRegisterDefinition(t *testing.T) {
+	defer func(handlers []customHandler) { customHandlers = handlers }(customHandlers)
+	RegisterDefinition(func(ddl ast.DDL) bool {
+		_, ok := ddl.(*ast.CreatePlacement)
+		return ok
+	}, func(ddl ast.DDL) []Definition {
+		return []Definition{testPlacement{ddl.(*ast.CreatePlacement)}}
+	})
+
+	for name, tt := range map[string]struct {
+		base     string
+		target   string
+		wantDDLs string
+	}{
+		"add": {
+			"",
+			`CREATE PLACEMENT P1 OPTIONS (instance_partition = "p1");`,
+			`CREATE PLACEMENT P1 OPTIONS (instance_partition = "p1");`,
+		},
+		"no change": {
+			`CREATE PLACEMENT P1 OPTIONS (instance_partition = "p1");`,
+			`CREATE PLACEMENT P1 OPTIONS (instance_partition = "p1");`,
+			"",
+		},
+		"recreate": {
+			`CREATE PLACEMENT P1 OPTIONS (instance_partition = "p1");`,
+			`CREATE PLACEMENT P1 OPTIONS (instance_partition = "p2");`,
+			`CREATE PLACEMENT P1 OPTIONS (instance_partition = "p2");`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Diff(strings.NewReader(tt.base), strings.NewReader(tt.target), &buf, DiffOption{
+				ErrorOnUnsupportedDDL: true,
+				Printer:               NoStylePrinter{},
+			})
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			equalDDLs(t, tt.wantDDLs, buf.String())
+		})
+	}
+}
+
+func TestPlanOperations(t *testing.T) {
+	ops, _, err := PlanOperations(strings.NewReader(``), strings.NewReader(`
+		CREATE TABLE T1 (
+		  T1_I1 INT64 NOT NULL,
+		) PRIMARY KEY(T1_I1);
+		CREATE INDEX T1_IDX1 ON T1(T1_I1);`), DiffOption{})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	got, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	want := `[` +
+		`{"kind":"add","id":"Table(T1)","ddl":"CREATE TABLE T1 (\n  T1_I1 INT64 NOT NULL\n) PRIMARY KEY (T1_I1)","dependsOn":[]},` +
+		`{"kind":"add","id":"Index(T1_IDX1)","ddl":"CREATE INDEX T1_IDX1 ON T1(T1_I1)","dependsOn":["Table(T1):Column(T1_I1)","Table(T1)"]}` +
+		`]`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("diff (-want +got):\n%s", diff)
+	}
+}
+
+func BenchmarkDiff_WideTable(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE T1 (\n  T1_I1 INT64 NOT NULL,\n")
+	for i := range 1000 {
+		fmt.Fprintf(&sb, "  T1_S%d STRING(MAX) NOT NULL DEFAULT (\"a\") OPTIONS (allow_commit_timestamp = false),\n", i)
+	}
+	sb.WriteString(") PRIMARY KEY(T1_I1);")
+	schema := sb.String()
+
+	b.ResetTimer()
+	for range b.N {
+		err := Diff(strings.NewReader(schema), strings.NewReader(schema), io.Discard, DiffOption{})
+		if err != nil {
+			b.Fatal(err)
+		}
 	}
 }
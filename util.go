@@ -2,6 +2,11 @@ package spannerdiff
 
 import (
 	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/cloudspannerecosystem/memefish/ast"
 	"github.com/cloudspannerecosystem/memefish/token"
@@ -43,6 +48,14 @@ func (o optional[T]) or(a optional[T]) optional[T] {
 func equalNode(a, b ast.Node) bool {
 	return cmp.Equal(a, b,
 		cmpopts.IgnoreTypes(token.Pos(0)),
+		// IF NOT EXISTS and OR REPLACE are how to apply the statement, not a part of the schema.
+		cmpopts.IgnoreFields(ast.CreateTable{}, "IfNotExists"),
+		cmpopts.IgnoreFields(ast.CreateSequence{}, "IfNotExists"),
+		cmpopts.IgnoreFields(ast.CreateIndex{}, "IfNotExists"),
+		cmpopts.IgnoreFields(ast.CreateVectorIndex{}, "IfNotExists"),
+		cmpopts.IgnoreFields(ast.CreateView{}, "OrReplace"),
+		cmpopts.IgnoreFields(ast.CreateModel{}, "OrReplace", "IfNotExists"),
+		cmpopts.IgnoreFields(ast.CreatePropertyGraph{}, "OrReplace", "IfNotExists"),
 		cmp.Comparer(func(a, b *ast.Options) bool {
 			if a == nil && b == nil {
 				return true
@@ -54,10 +67,10 @@ func equalNode(a, b ast.Node) bool {
 			ma := make(map[string]ast.Expr)
 			mb := make(map[string]ast.Expr)
 			for _, o := range a.Records {
-				ma[o.Name.Name] = o.Value
+				ma[o.Name.Name] = normalizeOptionValue(o.Name.Name, o.Value)
 			}
 			for _, o := range b.Records {
-				mb[o.Name.Name] = o.Value
+				mb[o.Name.Name] = normalizeOptionValue(o.Name.Name, o.Value)
 			}
 			return cmp.Equal(ma, mb, cmpopts.IgnoreTypes(token.Pos(0)))
 		}),
@@ -72,9 +85,128 @@ func equalNode(a, b ast.Node) bool {
 			}
 			return cmp.Equal(aVal, bVal, cmpopts.IgnoreTypes(token.Pos(0)))
 		}),
+		cmp.Comparer(func(a, b *ast.OrderByItem) bool {
+			if a == nil || b == nil {
+				return a == b
+			}
+			// ORDER BY of search indexes and queries is ascending by default.
+			aDir, bDir := a.Dir, b.Dir
+			if aDir == "" {
+				aDir = ast.DirectionAsc
+			}
+			if bDir == "" {
+				bDir = ast.DirectionAsc
+			}
+			return aDir == bDir && equalNode(a.Expr, b.Expr) && equalNode(a.Collate, b.Collate)
+		}),
+		cmp.Comparer(func(a, b *ast.GeneratedColumnExpr) bool {
+			if a == nil || b == nil {
+				return a == b
+			}
+			// STORED is represented only by its position.
+			if a.Stored.Invalid() != b.Stored.Invalid() {
+				return false
+			}
+			return equalNode(a.Expr, b.Expr)
+		}),
+		cmp.Comparer(func(a, b *ast.ChangeStreamForTable) bool {
+			if a == nil || b == nil {
+				return a == b
+			}
+			// T() is distinguished from T only by the position of ")".
+			if a.Rparen.Invalid() != b.Rparen.Invalid() {
+				return false
+			}
+			return equalNode(a.TableName, b.TableName) && equalNodes(a.Columns, b.Columns)
+		}),
+		cmp.Comparer(func(a, b *ast.RowDeletionPolicy) bool {
+			if a == nil || b == nil {
+				return a == b
+			}
+			// INTERVAL 30 DAY and INTERVAL 0x1E DAY are the same policy.
+			return equalNode(a.ColumnName, b.ColumnName) && equalIntLiteral(a.NumDays, b.NumDays)
+		}),
 	)
 }
 
+// equalIntLiteral reports whether a and b have the same value regardless of the notation.
+func equalIntLiteral(a, b *ast.IntLiteral) bool {
+	va, errA := intLiteralValue(a)
+	vb, errB := intLiteralValue(b)
+	if errA != nil || errB != nil {
+		return a.Value == b.Value
+	}
+	return va == vb
+}
+
+func intLiteralValue(l *ast.IntLiteral) (int64, error) {
+	if l.Base == 16 {
+		v := strings.TrimPrefix(strings.ToLower(l.Value), "0x")
+		return strconv.ParseInt(v, 16, 64)
+	}
+	return strconv.ParseInt(l.Value, 10, 64)
+}
+
+// unorderedArrayOptions is the list of options whose array value has no meaningful order.
+var unorderedArrayOptions = []string{
+	"endpoints", // CREATE MODEL: the endpoints are chosen at random.
+}
+
+// integerOptions is the list of options whose value is an integer, which can be written as 10, 0xA or 10.0.
+var integerOptions = []string{
+	"optimizer_version",
+	"default_batch_size",
+	"skip_range_min",
+	"skip_range_max",
+	"start_counter_with",
+}
+
+// normalizeOptionValue converts the value of the option to the canonical form if the representation doesn't matter.
+func normalizeOptionValue(name string, value ast.Expr) ast.Expr {
+	switch v := value.(type) {
+	case *ast.ArrayLiteral:
+		if !slices.Contains(unorderedArrayOptions, name) {
+			return value
+		}
+		sorted := *v
+		sorted.Values = slices.Clone(v.Values)
+		slices.SortFunc(sorted.Values, func(a, b ast.Expr) int {
+			return strings.Compare(a.SQL(), b.SQL())
+		})
+		return &sorted
+	case *ast.IntLiteral:
+		if !slices.Contains(integerOptions, name) {
+			return value
+		}
+		if i, err := intLiteralValue(v); err == nil {
+			return &ast.IntLiteral{Base: 10, Value: strconv.FormatInt(i, 10)}
+		}
+		return value
+	case *ast.FloatLiteral:
+		if !slices.Contains(integerOptions, name) {
+			return value
+		}
+		f, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return value
+		}
+		return &ast.IntLiteral{Base: 10, Value: strconv.FormatInt(int64(f), 10)}
+	default:
+		return value
+	}
+}
+
+// equalDefinition reports whether a and b define the same schema.
+// Columns of wide tables are mostly unchanged, so their rendered SQL is compared before the slow reflective comparison.
+func equalDefinition(a, b definition) bool {
+	if ca, ok := a.(*column); ok {
+		if cb, ok := b.(*column); ok && ca.sql() == cb.sql() {
+			return true
+		}
+	}
+	return equalNode(a.astNode(), b.astNode())
+}
+
 func equalNodes[T ast.Node](a, b []T) bool {
 	if len(a) != len(b) {
 		return false
@@ -87,16 +219,28 @@ func equalNodes[T ast.Node](a, b []T) bool {
 	return true
 }
 
-func columnTypeOf(a ast.SchemaType) columnType {
+// columnTypeOf returns the type of a column. A named type is an ENUM if it is in enumTypes, or a PROTO otherwise.
+func columnTypeOf(a ast.SchemaType, enumTypes []string) columnType {
 	switch a := a.(type) {
 	case *ast.ArraySchemaType:
-		return array{columnTypeOf(a.Item)}
+		return array{columnTypeOf(a.Item, enumTypes)}
 	case *ast.ScalarSchemaType:
 		return scalar{a.Name}
 	case *ast.SizedSchemaType:
 		return scalar{a.Name}
 	case *ast.NamedType:
-		return protoOrEnum{}
+		if len(enumTypes) == 0 {
+			// Whether a named type is a PROTO or an ENUM is defined in the proto descriptors, which are not part of the schema.
+			return protoOrEnum{}
+		}
+		var names []string
+		for _, ident := range a.Path {
+			names = append(names, ident.Name)
+		}
+		if slices.Contains(enumTypes, strings.Join(names, ".")) {
+			return enum{}
+		}
+		return proto{}
 	default:
 		panic(fmt.Sprintf("unexpected column type: %s", a.SQL()))
 	}
@@ -110,6 +254,8 @@ var _ = []struct{}{
 	isComparable(scalar{}),
 	isComparable(array{}),
 	isComparable(protoOrEnum{}),
+	isComparable(proto{}),
+	isComparable(enum{}),
 }
 
 type scalar struct {
@@ -128,6 +274,14 @@ type protoOrEnum struct{}
 
 func (n protoOrEnum) isColumnType() {}
 
+type proto struct{}
+
+func (p proto) isColumnType() {}
+
+type enum struct{}
+
+func (e enum) isColumnType() {}
+
 type tuple struct {
 	first  columnType
 	second columnType
@@ -149,6 +303,24 @@ func unique[T comparable](is []T) []T {
 	return result
 }
 
+// equalSet reports whether a and b have the same elements regardless of the order.
+// Elements must be unique in each slice.
+func equalSet[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	m := make(map[T]struct{}, len(a))
+	for _, v := range a {
+		m[v] = struct{}{}
+	}
+	for _, v := range b {
+		if _, ok := m[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func uniqueByFunc[A any, B comparable](is []A, f func(A) B) []A {
 	m := make(map[B]A)
 	result := make([]A, 0, len(is))
@@ -183,3 +355,191 @@ func tablesOrViewsInQueryExpr(expr ast.QueryExpr) ([]*ast.Path, []*ast.Ident) {
 	})
 	return paths, idents
 }
+
+// columnsInExpr returns the column names referenced by the expression.
+func columnsInExpr(expr ast.Expr) []*ast.Ident {
+	var idents []*ast.Ident
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Path:
+			// The function name of a call.
+			return false
+		case *ast.Ident:
+			idents = append(idents, n)
+		}
+		return true
+	})
+	return idents
+}
+
+// renameSchema replaces the schema of qualified names, e.g. S1.T1 to S2.T1 if from is S1 and to is S2.
+func renameSchema(ddls []ast.DDL, from, to string) {
+	ast.InspectMany(ddls, func(n ast.Node) bool {
+		if p, ok := n.(*ast.Path); ok && len(p.Idents) == 2 && p.Idents[0].Name == from {
+			p.Idents[0].Name = to
+		}
+		return true
+	})
+}
+
+// unqualifyDefaultSchema removes the default schema from the names of tables, indexes, views and sequences,
+// e.g. S.T1 to T1 if S is the default schema.
+// Names in view queries and expressions, such as check constraints and defaults, are kept as written.
+func unqualifyDefaultSchema(ddls []ast.DDL, defaultSchema string) {
+	unqualify := func(p *ast.Path) {
+		if p != nil && len(p.Idents) == 2 && p.Idents[0].Name == defaultSchema {
+			p.Idents = p.Idents[1:]
+		}
+	}
+	ast.InspectMany(ddls, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CreateTable:
+			unqualify(n.Name)
+		case *ast.DropTable:
+			unqualify(n.Name)
+		case *ast.AlterTable:
+			unqualify(n.Name)
+		case *ast.Cluster:
+			unqualify(n.TableName)
+		case *ast.SetInterleaveIn:
+			unqualify(n.TableName)
+		case *ast.ForeignKey:
+			unqualify(n.ReferenceTable)
+		case *ast.CreateIndex:
+			unqualify(n.Name)
+			unqualify(n.TableName)
+		case *ast.DropIndex:
+			unqualify(n.Name)
+		case *ast.AlterIndex:
+			unqualify(n.Name)
+		case *ast.CreateView:
+			unqualify(n.Name)
+			// The query is not a name of the DDL.
+			return false
+		case *ast.DropView:
+			unqualify(n.Name)
+		case *ast.CreateSequence:
+			unqualify(n.Name)
+		case *ast.AlterSequence:
+			unqualify(n.Name)
+		case *ast.DropSequence:
+			unqualify(n.Name)
+		}
+		return true
+	})
+}
+
+// qualifySchema prefixes the unqualified names of tables, indexes and views with the schema, e.g. T1 to S.T1.
+// Names in view queries and in statements naming objects by an identifier, such as GRANT, are not qualified.
+func qualifySchema(ddls []ast.DDL, schema string) {
+	qualify := func(p *ast.Path) {
+		if p != nil && len(p.Idents) == 1 {
+			p.Idents = []*ast.Ident{{Name: schema}, p.Idents[0]}
+		}
+	}
+	ast.InspectMany(ddls, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CreateTable:
+			qualify(n.Name)
+		case *ast.DropTable:
+			qualify(n.Name)
+		case *ast.AlterTable:
+			qualify(n.Name)
+		case *ast.Cluster:
+			qualify(n.TableName)
+		case *ast.ForeignKey:
+			qualify(n.ReferenceTable)
+		case *ast.CreateIndex:
+			qualify(n.Name)
+			qualify(n.TableName)
+		case *ast.DropIndex:
+			qualify(n.Name)
+		case *ast.AlterIndex:
+			qualify(n.Name)
+		case *ast.CreateView:
+			qualify(n.Name)
+		case *ast.DropView:
+			qualify(n.Name)
+		}
+		return true
+	})
+}
+
+// optionsToSet returns options to change base to target.
+// Options only in base are set to NULL to reset them to default.
+func optionsToSet(base, target *ast.Options) *ast.Options {
+	var records []*ast.OptionsDef
+	if target != nil {
+		records = append(records, target.Records...)
+	}
+	if base != nil {
+		for _, r := range base.Records {
+			if target != nil {
+				if _, ok := target.Field(r.Name.Name); ok {
+					continue
+				}
+			}
+			records = append(records, &ast.OptionsDef{Name: r.Name, Value: &ast.NullLiteral{}})
+		}
+	}
+	return &ast.Options{Records: records}
+}
+
+func localityGroupIDOf(options *ast.Options) optional[localityGroupID] {
+	if options == nil {
+		return none[localityGroupID]()
+	}
+	name, err := options.StringField("locality_group")
+	if err != nil || name == nil {
+		return none[localityGroupID]()
+	}
+	return some(newLocalityGroupID(*name))
+}
+
+// immutableOptions returns the option keys which can't be changed by SET OPTIONS on the type of def.
+// Changing any of them requires recreating def, while the other options, including unknown ones, are altered by SET OPTIONS.
+func immutableOptions(def definition) []string {
+	switch def.(type) {
+	case *sequence:
+		return []string{"sequence_kind"}
+	case *changeStream:
+		return []string{"partition_mode"}
+	default:
+		return nil
+	}
+}
+
+// canAlterOptions reports whether all the differences between the base and the target options of def can be applied by SET OPTIONS.
+func canAlterOptions(def definition, base, target *ast.Options) bool {
+	for _, name := range immutableOptions(def) {
+		if !equalOption(base, target, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalOption reports whether the option of the name is the same in a and b.
+func equalOption(a, b *ast.Options, name string) bool {
+	var va, vb ast.Expr
+	if a != nil {
+		va, _ = a.Field(name)
+	}
+	if b != nil {
+		vb, _ = b.Field(name)
+	}
+	return equalNode(normalizeOptionValue(name, va), normalizeOptionValue(name, vb))
+}
+
+// postgreSQLColumnPattern matches a column definition using a type name only the PostgreSQL dialect has.
+var postgreSQLColumnPattern = regexp.MustCompile(`(?im)^\s*"?\w+"?\s+(bigint|boolean|bytea|character varying|double precision|jsonb|text|timestamptz|timestamp with time zone|varchar)\b`)
+
+// postgreSQLStatement returns the first statement of sql which looks like the PostgreSQL dialect.
+func postgreSQLStatement(sql string) (string, bool) {
+	for _, stmt := range strings.Split(sql, ";") {
+		if postgreSQLColumnPattern.MatchString(stmt) {
+			return strings.TrimSpace(stmt), true
+		}
+	}
+	return "", false
+}